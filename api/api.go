@@ -28,8 +28,18 @@ func NewAPI(e *echo.Echo, rag *rag.Rag) {
 	api := e.Group(fmt.Sprintf("/api/%s", APIVersion))
 
 	api.POST("/upload", UploadHandler)
+	api.POST("/upload/file", UploadFileHandler)
+	api.POST("/upload/url", UploadURLHandler)
+	api.GET("/task/:id", GetTaskHandler)
 	api.POST("/ask", AskDocHandler)
+	api.POST("/ask/stream", AskStreamHandler)
+	api.POST("/search", SearchHandler)
 	api.GET("/docs", ListAllDocsHandler)
 	api.GET("/doc/:id", GetDocHandler)
+	api.PUT("/doc/:id", UpdateDocHandler)
+	api.PATCH("/doc/:id", PatchDocHandler)
+	api.GET("/doc/:id/versions", ListVersionsHandler)
+	api.GET("/doc/:id/versions/:version", GetVersionHandler)
 	api.DELETE("/doc/:id", DeleteDocHandler)
+	api.GET("/embeddings/cache", EmbeddingCacheHandler)
 }
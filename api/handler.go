@@ -1,31 +1,54 @@
 package api
 
 import (
-	"fmt"
-	"log"
+	"errors"
 	"net/http"
 
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/embeddings"
 	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/fetch"
 	"github.com/elchemista/easy_rag/internal/pkg/rag"
-	"github.com/elchemista/easy_rag/internal/pkg/textprocessor"
-	"github.com/google/uuid"
+	"github.com/elchemista/easy_rag/internal/pkg/task"
 	"github.com/labstack/echo/v4"
 )
 
 type UploadDoc struct {
-	Content  string            `json:"content"`
-	Link     string            `json:"link"`
-	Filename string            `json:"filename"`
-	Category string            `json:"category"`
-	Metadata map[string]string `json:"metadata"`
+	Content    string            `json:"content"`
+	Link       string            `json:"link"` // Fetched when the content is empty
+	Filename   string            `json:"filename"`
+	Category   string            `json:"category"`
+	Metadata   map[string]string `json:"metadata"`
+	Chunking   *models.Chunking  `json:"chunking"`    // The chunking of the request if unset
+	ExternalID string            `json:"external_id"` // Matches the document with earlier uploads of the same ID
+	Mode       string            `json:"mode"`        // The mode of the request if unset
 }
 
 type RequestUpload struct {
-	Docs []UploadDoc `json:"docs"`
+	Docs     []UploadDoc      `json:"docs"`
+	Chunking *models.Chunking `json:"chunking"` // Chunking of the documents, the configured one if unset
+	Mode     string           `json:"mode"`     // skip | replace | new_version when a document was already uploaded, the configured one if unset
 }
 
 type RequestQuestion struct {
-	Question string `json:"question"`
+	Question      string        `json:"question"`
+	Filter        models.Filter `json:"filter"`         // Restricts the answer to the matching documents
+	KeywordWeight *float64      `json:"keyword_weight"` // Share of the keyword search, the configured one if unset
+	MinScore      *float32      `json:"min_score"`      // Minimum similarity of the chunks, the configured one if unset
+}
+
+// retrieveOptions returns the retrieval options of the question
+func (r RequestQuestion) retrieveOptions() rag.RetrieveOptions {
+	return rag.RetrieveOptions{Filter: r.Filter, KeywordWeight: r.KeywordWeight, MinScore: r.MinScore}
+}
+
+type RequestSearch struct {
+	Query      string        `json:"query"`
+	Vector     []float32     `json:"vector"` // Used instead of the query when set
+	TopK       int           `json:"top_k"`
+	Filter     models.Filter `json:"filter"`
+	MinScore   float32       `json:"min_score"`
+	Neighbours int           `json:"neighbours"` // Neighbouring chunks returned on each side of a hit
 }
 
 type ResposeQuestion struct {
@@ -43,114 +66,81 @@ func UploadHandler(c echo.Context) error {
 		return ErrorHandler(err, c)
 	}
 
-	// Generate a unique task ID
-	taskID := uuid.NewString()
-
-	// Launch the upload process in a separate goroutine
-	go func(taskID string, request RequestUpload) {
-		log.Printf("Task %s: started processing", taskID)
-		defer log.Printf("Task %s: completed processing", taskID)
-
-		var docs []models.Document
-
-		for idx, doc := range request.Docs {
-			// Generate a unique ID for each document
-			docID := uuid.NewString()
-			log.Printf("Task %s: processing document %d with generated ID %s (filename: %s)", taskID, idx, docID, doc.Filename)
-
-			// Step 1: Create chunks from document content
-			chunks := textprocessor.CreateChunks(doc.Content)
-			log.Printf("Task %s: created %d chunks for document %s", taskID, len(chunks), docID)
-
-			// Step 2: Generate summary for the document
-			var summaryChunks string
-			if len(chunks) < 4 {
-				summaryChunks = doc.Content
-			} else {
-				summaryChunks = textprocessor.ConcatenateStrings(chunks[:3])
+	docs := make([]models.Document, len(request.Docs))
+	for idx, doc := range request.Docs {
+		// a document with a link and no content is fetched by the ingestion workers
+		if doc.Content == "" && doc.Link != "" {
+			if err := fetch.ValidateURL(doc.Link); err != nil {
+				return ErrorHandler(err, c)
 			}
+		}
 
-			log.Printf("Task %s: generating summary for document %s", taskID, docID)
-			summary, err := rag.LLM.Generate(fmt.Sprintf("Give me only summary of the following text: %s", summaryChunks))
-			if err != nil {
-				log.Printf("Task %s: error generating summary for document %s: %v", taskID, docID, err)
-				return
-			}
-			log.Printf("Task %s: generated summary for document %s", taskID, docID)
-
-			// Step 3: Vectorize the summary
-			log.Printf("Task %s: vectorizing summary for document %s", taskID, docID)
-			vectorSum, err := rag.Embeddings.Vectorize(summary)
-			if err != nil {
-				log.Printf("Task %s: error vectorizing summary for document %s: %v", taskID, docID, err)
-				return
-			}
-			log.Printf("Task %s: vectorized summary for document %s", taskID, docID)
-
-			// Step 4: Save the document
-			document := models.Document{
-				ID:             docID, // Use generated ID
-				Content:        "",
-				Link:           doc.Link,
-				Filename:       doc.Filename,
-				Category:       doc.Category,
-				EmbeddingModel: rag.Embeddings.GetModel(),
-				Summary:        summary,
-				Vector:         vectorSum[0],
-				Metadata:       doc.Metadata,
-			}
-			log.Printf("Task %s: saving document %s", taskID, docID)
-			if err := rag.Database.SaveDocument(document); err != nil {
-				log.Printf("Task %s: error saving document %s: %v", taskID, docID, err)
-				return
-			}
-			log.Printf("Task %s: saved document %s", taskID, docID)
-
-			// Step 5: Process and save embeddings for each chunk
-			var embeddings []models.Embedding
-			for order, chunk := range chunks {
-				log.Printf("Task %s: vectorizing chunk %d for document %s", taskID, order, docID)
-				vectorEmbedding, err := rag.Embeddings.Vectorize(chunk)
-				if err != nil {
-					log.Printf("Task %s: error vectorizing chunk %d for document %s: %v", taskID, order, docID, err)
-					return
-				}
-				log.Printf("Task %s: vectorized chunk %d for document %s", taskID, order, docID)
-
-				embedding := models.Embedding{
-					ID:         uuid.NewString(),
-					DocumentID: docID,
-					Vector:     vectorEmbedding[0],
-					TextChunk:  chunk,
-					Dimension:  int64(1024),
-					Order:      int64(order),
-				}
-				embeddings = append(embeddings, embedding)
-			}
+		chunking := doc.Chunking
+		if chunking == nil {
+			chunking = request.Chunking
+		}
+		resolved, err := rag.ResolveChunking(chunking)
+		if err != nil {
+			return ErrorHandler(err, c)
+		}
 
-			log.Printf("Task %s: saving %d embeddings for document %s", taskID, len(embeddings), docID)
-			if err := rag.Database.SaveEmbeddings(embeddings); err != nil {
-				log.Printf("Task %s: error saving embeddings for document %s: %v", taskID, docID, err)
-				return
-			}
-			log.Printf("Task %s: saved embeddings for document %s", taskID, docID)
+		mode := doc.Mode
+		if mode == "" {
+			mode = request.Mode
+		}
+		mode, err = rag.ResolveMode(mode)
+		if err != nil {
+			return ErrorHandler(err, c)
+		}
 
-			docs = append(docs, document)
+		docs[idx] = models.Document{
+			Content:    doc.Content,
+			Link:       doc.Link,
+			Filename:   doc.Filename,
+			Category:   doc.Category,
+			Metadata:   doc.Metadata,
+			Chunking:   &resolved,
+			ExternalID: doc.ExternalID,
+			Mode:       mode,
 		}
-	}(taskID, request)
+	}
+
+	// Queue the documents, the ingestion workers process them in the background
+	info, err := rag.Enqueue(docs)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
 
 	// Return the task ID and expected completion time
 	return c.JSON(http.StatusAccepted, map[string]interface{}{
 		"version":       APIVersion,
-		"task_id":       taskID,
+		"task_id":       info.ID,
 		"expected_time": "10m",
 		"status":        "Processing started",
 	})
 }
 
+func GetTaskHandler(c echo.Context) error {
+	rag := c.Get("Rag").(*rag.Rag)
+	id := c.Param("id")
+	info, err := rag.Tasks.Get(id)
+	if errors.Is(err, task.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"version": APIVersion,
+		"task":    info,
+	})
+}
+
 func ListAllDocsHandler(c echo.Context) error {
 	rag := c.Get("Rag").(*rag.Rag)
-	docs, err := rag.Database.ListDocuments()
+	docs, err := rag.Database.ListDocuments(c.Request().Context())
 	if err != nil {
 		return ErrorHandler(err, c)
 	}
@@ -163,9 +153,9 @@ func ListAllDocsHandler(c echo.Context) error {
 func GetDocHandler(c echo.Context) error {
 	rag := c.Get("Rag").(*rag.Rag)
 	id := c.Param("id")
-	doc, err := rag.Database.GetDocument(id)
+	doc, err := rag.Database.GetDocument(c.Request().Context(), id)
 	if err != nil {
-		return ErrorHandler(err, c)
+		return DocumentErrorHandler(err, c)
 	}
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"version": APIVersion,
@@ -183,55 +173,74 @@ func AskDocHandler(c echo.Context) error {
 		return ErrorHandler(err, c)
 	}
 
-	questionV, err := rag.Embeddings.Vectorize(request.Question)
+	answer, err := rag.Ask(c.Request().Context(), request.Question, request.retrieveOptions())
 
 	if err != nil {
 		return ErrorHandler(err, c)
 	}
 
-	embeddings, err := rag.Database.Search(questionV)
-
-	if err != nil {
-		return ErrorHandler(err, c)
-	}
-
-	if len(embeddings) == 0 {
+	if len(answer.Sources) == 0 {
 		return c.JSON(http.StatusOK, map[string]interface{}{
-			"version": APIVersion,
-			"docs":    nil,
-			"answer":  "Don't found any relevant documents",
+			"version":   APIVersion,
+			"docs":      nil,
+			"answer":    "Don't found any relevant documents",
+			"citations": []interface{}{},
 		})
 	}
 
-	answer, err := rag.LLM.Generate(fmt.Sprintf("Given the following information: %s \nAnswer the question: %s", embeddings[0].TextChunk, request.Question))
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"version":   APIVersion,
+		"docs":      citedDocuments(answer.Citations),
+		"answer":    answer.Text,
+		"citations": answer.Citations,
+	})
+}
+
+// SearchHandler returns the chunks most similar to a query or a vector without calling the LLM
+func SearchHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
 
-	if err != nil {
+	var request RequestSearch
+	if err := c.Bind(&request); err != nil {
 		return ErrorHandler(err, c)
 	}
 
-	// Use a map to track unique DocumentIDs
-	docSet := make(map[string]struct{})
-	for _, embedding := range embeddings {
-		docSet[embedding.DocumentID] = struct{}{}
-	}
-
-	// Convert the map keys to a slice
-	docs := make([]string, 0, len(docSet))
-	for docID := range docSet {
-		docs = append(docs, docID)
+	hits, err := r.Search(c.Request().Context(), rag.SearchQuery{
+		Query:      request.Query,
+		Vector:     request.Vector,
+		TopK:       request.TopK,
+		Filter:     request.Filter,
+		MinScore:   request.MinScore,
+		Neighbours: request.Neighbours,
+	})
+	if err != nil {
+		return ErrorHandler(err, c)
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"version": APIVersion,
-		"docs":    docs,
-		"answer":  answer,
+		"results": hits,
 	})
 }
 
+// citedDocuments returns the unique document IDs of the citations, in citation order
+func citedDocuments(citations []rag.Citation) []string {
+	docSet := make(map[string]struct{})
+	docs := make([]string, 0, len(citations))
+	for _, citation := range citations {
+		if _, ok := docSet[citation.DocumentID]; ok {
+			continue
+		}
+		docSet[citation.DocumentID] = struct{}{}
+		docs = append(docs, citation.DocumentID)
+	}
+	return docs
+}
+
 func DeleteDocHandler(c echo.Context) error {
 	rag := c.Get("Rag").(*rag.Rag)
 	id := c.Param("id")
-	err := rag.Database.DeleteDocument(id)
+	err := rag.DeleteDocument(c.Request().Context(), id)
 	if err != nil {
 		return ErrorHandler(err, c)
 	}
@@ -241,6 +250,31 @@ func DeleteDocHandler(c echo.Context) error {
 	})
 }
 
+// EmbeddingCacheHandler returns the counters of the embedding cache
+func EmbeddingCacheHandler(c echo.Context) error {
+	rag := c.Get("Rag").(*rag.Rag)
+	cache, ok := rag.Embeddings.(*embeddings.CachedEmbeddings)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": "embedding cache disabled",
+		})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"version": APIVersion,
+		"cache":   cache.Stats(),
+	})
+}
+
+// DocumentErrorHandler answers 404 when the requested document doesn't exist, like ErrorHandler otherwise
+func DocumentErrorHandler(err error, c echo.Context) error {
+	if errors.Is(err, database.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return ErrorHandler(err, c)
+}
+
 func ErrorHandler(err error, c echo.Context) error {
 	return c.JSON(http.StatusBadRequest, map[string]interface{}{
 		"error": err.Error(),
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/pkg/rag"
+	"github.com/labstack/echo/v4"
+)
+
+// AskStreamHandler answers a question as Server-Sent Events: a "sources" event with the chunks
+// given to the LLM, "delta" events with the generated text and a final "done" event with the
+// citations and timing. Failures after the stream started are sent as an "error" event.
+func AskStreamHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
+
+	var request RequestQuestion
+	if err := c.Bind(&request); err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	start := time.Now()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	res.WriteHeader(http.StatusOK)
+
+	answer, err := r.AskStream(c.Request().Context(), request.Question, request.retrieveOptions(),
+		func(sources []rag.Source) error {
+			return writeEvent(res, "sources", map[string]interface{}{
+				"version": APIVersion,
+				"sources": rag.SourceCitations(sources),
+			})
+		},
+		func(delta string) error {
+			return writeEvent(res, "delta", map[string]interface{}{
+				"text": delta,
+			})
+		},
+	)
+	if err != nil {
+		return writeEvent(res, "error", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	text := answer.Text
+	if len(answer.Sources) == 0 {
+		text = "Don't found any relevant documents"
+	}
+
+	return writeEvent(res, "done", map[string]interface{}{
+		"version":   APIVersion,
+		"docs":      citedDocuments(answer.Citations),
+		"answer":    text,
+		"citations": answer.Citations,
+		"timing": map[string]int64{
+			"retrieval_ms":  answer.Timing.Retrieval.Milliseconds(),
+			"generation_ms": answer.Timing.Generation.Milliseconds(),
+			"total_ms":      time.Since(start).Milliseconds(),
+		},
+	})
+}
+
+// writeEvent writes a single Server-Sent Event with a JSON payload and flushes it to the client
+func writeEvent(res *echo.Response, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	res.Flush()
+
+	return nil
+}
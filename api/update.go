@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/rag"
+	"github.com/labstack/echo/v4"
+)
+
+type RequestUpdate struct {
+	Content  *string           `json:"content"` // Re-indexed when set, only the changed chunks are embedded again
+	Link     *string           `json:"link"`
+	Filename *string           `json:"filename"`
+	Category *string           `json:"category"`
+	Metadata map[string]string `json:"metadata"` // Replaces the metadata when set
+	Chunking *models.Chunking  `json:"chunking"` // Chunking of the new content, the one of the document if unset
+}
+
+type RequestPatch struct {
+	Link     *string            `json:"link"`
+	Filename *string            `json:"filename"`
+	Category *string            `json:"category"`
+	Metadata map[string]*string `json:"metadata"` // Keys set to their value, removed when null
+}
+
+// UpdateDocHandler replaces the content and/or the fields of a document, keeping its ID.
+// A new content is re-indexed in the background like an upload, the other fields are
+// updated right away.
+func UpdateDocHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
+	id := c.Param("id")
+
+	var request RequestUpdate
+	if err := c.Bind(&request); err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	update := rag.DocumentUpdate{
+		FieldUpdate: models.FieldUpdate{
+			Link:            request.Link,
+			Filename:        request.Filename,
+			Category:        request.Category,
+			ReplaceMetadata: request.Metadata != nil,
+		},
+		Content:  request.Content,
+		Chunking: request.Chunking,
+	}
+	if request.Metadata != nil {
+		update.Metadata = make(map[string]*string, len(request.Metadata))
+		for key, value := range request.Metadata {
+			update.Metadata[key] = &value
+		}
+	}
+
+	if request.Content == nil {
+		if request.Chunking != nil {
+			return ErrorHandler(errors.New("chunking applies to a new content, set content"), c)
+		}
+		doc, err := r.UpdateMetadata(c.Request().Context(), id, update)
+		if err != nil {
+			return DocumentErrorHandler(err, c)
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"version": APIVersion,
+			"doc":     doc,
+		})
+	}
+
+	// Queue the new content, the ingestion workers re-index it in the background
+	info, err := r.EnqueueUpdate(c.Request().Context(), id, update)
+	if err != nil {
+		return DocumentErrorHandler(err, c)
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"version":       APIVersion,
+		"task_id":       info.ID,
+		"expected_time": "10m",
+		"status":        "Processing started",
+	})
+}
+
+// PatchDocHandler updates the link, filename, category and metadata of a document and of its
+// chunks without touching their vectors
+func PatchDocHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
+	id := c.Param("id")
+
+	var request RequestPatch
+	if err := c.Bind(&request); err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	doc, err := r.UpdateMetadata(c.Request().Context(), id, rag.DocumentUpdate{FieldUpdate: models.FieldUpdate{
+		Link:     request.Link,
+		Filename: request.Filename,
+		Category: request.Category,
+		Metadata: request.Metadata,
+	}})
+	if err != nil {
+		return DocumentErrorHandler(err, c)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"version": APIVersion,
+		"doc":     doc,
+	})
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/extract"
+	"github.com/elchemista/easy_rag/internal/pkg/rag"
+	"github.com/labstack/echo/v4"
+)
+
+// MaxUploadFileSize is the largest file accepted by UploadFileHandler
+const MaxUploadFileSize = 32 << 20 // 32 MiB
+
+// MaxUploadFileRequestSize is the largest request accepted by UploadFileHandler, its files
+// and form fields together. Reading stops at the limit, a larger request is refused.
+const MaxUploadFileRequestSize = MaxUploadFileSize + 1<<20
+
+// UploadFileHandler queues multipart files for ingestion. The text of each "files" part is
+// extracted according to its type, the optional "category", "link", "metadata" (JSON
+// object), "chunking" (JSON object) and "mode" fields apply to every file. The optional
+// "external_id" field is only accepted with a single file.
+func UploadFileHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, MaxUploadFileRequestSize)
+	form, err := c.MultipartForm()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return echo.ErrStatusRequestEntityTooLarge
+	}
+	if err != nil {
+		return ErrorHandler(fmt.Errorf("failed to read multipart form: %w", err), c)
+	}
+
+	files := form.File["files"]
+	if len(files) == 0 {
+		return ErrorHandler(errors.New("no file uploaded in the \"files\" field"), c)
+	}
+
+	var metadata map[string]string
+	if raw := c.FormValue("metadata"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &metadata); err != nil {
+			return ErrorHandler(fmt.Errorf("metadata must be a JSON object of strings: %w", err), c)
+		}
+	}
+
+	var chunking *models.Chunking
+	if raw := c.FormValue("chunking"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &chunking); err != nil {
+			return ErrorHandler(fmt.Errorf("chunking must be a JSON object: %w", err), c)
+		}
+	}
+	resolved, err := r.ResolveChunking(chunking)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	mode, err := r.ResolveMode(c.FormValue("mode"))
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	externalID := c.FormValue("external_id")
+	if externalID != "" && len(files) > 1 {
+		return ErrorHandler(errors.New("external_id identifies a single document, upload one file"), c)
+	}
+
+	docs := make([]models.Document, 0, len(files))
+	for _, header := range files {
+		if header.Size > MaxUploadFileSize {
+			return ErrorHandler(fmt.Errorf("file %s is larger than %d bytes", header.Filename, MaxUploadFileSize), c)
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			return ErrorHandler(fmt.Errorf("failed to open file %s: %w", header.Filename, err), c)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return ErrorHandler(fmt.Errorf("failed to read file %s: %w", header.Filename, err), c)
+		}
+
+		mimeType, sections, err := r.Extractors.Extract(header.Filename, header.Header.Get(echo.HeaderContentType), data)
+		if errors.Is(err, extract.ErrUnsupported) {
+			return c.JSON(http.StatusUnsupportedMediaType, map[string]interface{}{
+				"error": fmt.Sprintf("file %s: %v", header.Filename, err),
+			})
+		}
+		if err != nil {
+			return ErrorHandler(err, c)
+		}
+		if len(sections) == 0 {
+			return ErrorHandler(fmt.Errorf("no text found in file %s", header.Filename), c)
+		}
+
+		docMetadata := map[string]string{"content_type": mimeType}
+		for key, value := range metadata {
+			docMetadata[key] = value
+		}
+
+		docs = append(docs, models.Document{
+			Content:    extract.Content(sections),
+			Sections:   sections,
+			Link:       c.FormValue("link"),
+			Filename:   header.Filename,
+			Category:   c.FormValue("category"),
+			Metadata:   docMetadata,
+			Chunking:   &resolved,
+			ExternalID: externalID,
+			Mode:       mode,
+		})
+	}
+
+	// Queue the documents, the ingestion workers process them in the background
+	info, err := r.Enqueue(docs)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"version":       APIVersion,
+		"task_id":       info.ID,
+		"expected_time": "10m",
+		"status":        "Processing started",
+	})
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/fetch"
+	"github.com/elchemista/easy_rag/internal/pkg/rag"
+	"github.com/labstack/echo/v4"
+)
+
+type RequestUploadURL struct {
+	URLs     []string          `json:"urls"`
+	Sitemap  string            `json:"sitemap"` // Sitemap whose pages are added to the URLs
+	Category string            `json:"category"`
+	Metadata map[string]string `json:"metadata"`
+	Chunking *models.Chunking  `json:"chunking"` // The configured chunking if unset
+	Mode     string            `json:"mode"`     // Mode of the pages already uploaded, matched by URL, the configured one if unset
+}
+
+// UploadURLHandler queues web pages for ingestion. The pages are downloaded by the ingestion
+// workers, a sitemap is read right away to know the pages of the task. The URL of a page is
+// its external ID, so uploading it again applies the upload mode.
+func UploadURLHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
+
+	var request RequestUploadURL
+	if err := c.Bind(&request); err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	urls := request.URLs
+	if request.Sitemap != "" {
+		pages, err := r.Fetcher.Sitemap(c.Request().Context(), request.Sitemap)
+		if err != nil {
+			return ErrorHandler(err, c)
+		}
+		urls = append(urls, pages...)
+	}
+	if len(urls) == 0 {
+		return ErrorHandler(errors.New("no URL to fetch, set urls or sitemap"), c)
+	}
+
+	chunking, err := r.ResolveChunking(request.Chunking)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+	mode, err := r.ResolveMode(request.Mode)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	seen := make(map[string]bool, len(urls))
+	docs := make([]models.Document, 0, len(urls))
+	for _, url := range urls {
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+
+		if err := fetch.ValidateURL(url); err != nil {
+			return ErrorHandler(err, c)
+		}
+		if len(url) > rag.MaxURLLength {
+			return ErrorHandler(fmt.Errorf("URL %.64s... is longer than %d bytes", url, rag.MaxURLLength), c)
+		}
+		// the URL identifies the page across uploads
+		docs = append(docs, models.Document{
+			Link:       url,
+			Filename:   url,
+			Category:   request.Category,
+			Metadata:   request.Metadata,
+			Chunking:   &chunking,
+			ExternalID: url,
+			Mode:       mode,
+		})
+	}
+
+	// Queue the documents, the ingestion workers fetch and process them in the background
+	info, err := r.Enqueue(docs)
+	if err != nil {
+		return ErrorHandler(err, c)
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"version":       APIVersion,
+		"task_id":       info.ID,
+		"expected_time": "10m",
+		"status":        "Processing started",
+		"urls":          len(docs),
+	})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/elchemista/easy_rag/internal/pkg/history"
+	"github.com/elchemista/easy_rag/internal/pkg/rag"
+	"github.com/labstack/echo/v4"
+)
+
+// ListVersionsHandler returns the versions of a document without their content, oldest first
+func ListVersionsHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
+	id := c.Param("id")
+
+	versions, err := r.Versions(c.Request().Context(), id)
+	if err != nil {
+		return versionError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"version":     APIVersion,
+		"document_id": id,
+		"versions":    versions,
+	})
+}
+
+// GetVersionHandler returns a version of a document with its content
+func GetVersionHandler(c echo.Context) error {
+	r := c.Get("Rag").(*rag.Rag)
+	id := c.Param("id")
+
+	number, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil || number < 1 {
+		return ErrorHandler(fmt.Errorf("invalid version %q, expected a number from 1", c.Param("version")), c)
+	}
+
+	version, err := r.DocumentVersion(c.Request().Context(), id, number)
+	if err != nil {
+		return versionError(err, c)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"version":          APIVersion,
+		"document_id":      id,
+		"document_version": version,
+	})
+}
+
+// versionError answers 404 when the history is disabled or has no such document or version
+func versionError(err error, c echo.Context) error {
+	if errors.Is(err, rag.ErrHistoryDisabled) || errors.Is(err, history.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return DocumentErrorHandler(err, c)
+}
@@ -1,13 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/elchemista/easy_rag/api"
 	"github.com/elchemista/easy_rag/config"
-	"github.com/elchemista/easy_rag/internal/database"
 	"github.com/elchemista/easy_rag/internal/embeddings"
-	"github.com/elchemista/easy_rag/internal/llm"
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/bm25"
+	"github.com/elchemista/easy_rag/internal/pkg/fetch"
+	"github.com/elchemista/easy_rag/internal/pkg/history"
+	"github.com/elchemista/easy_rag/internal/pkg/provider"
+	"github.com/elchemista/easy_rag/internal/pkg/queue"
 	"github.com/elchemista/easy_rag/internal/pkg/rag"
+	"github.com/elchemista/easy_rag/internal/pkg/task"
+	"github.com/elchemista/easy_rag/internal/pkg/textprocessor"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 )
 
 // Rag is the main struct for the rag application
@@ -15,19 +32,178 @@ import (
 func main() {
 	cfg := config.NewConfig()
 
-	llm := llm.NewOllama(cfg.OllamaEndpoint, cfg.OllamaModel)
-	embeddings := embeddings.NewOllamaEmbeddings(cfg.OllamaEmbeddingEndpoint, cfg.OllamaEmbeddingModel)
-	database := database.NewMilvus(cfg.MilvusHost)
+	// cancelled on SIGINT/SIGTERM, stops startup calls and running ingestion jobs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	llm, err := provider.NewLLM(cfg)
+	if err != nil {
+		log.Fatalf("failed to configure LLM: %v", err)
+	}
+
+	embedder, err := provider.NewEmbeddings(cfg)
+	if err != nil {
+		log.Fatalf("failed to configure embeddings: %v", err)
+	}
+
+	var embeddingCache *embeddings.DiskCache
+	if cfg.EmbeddingCacheSize > 0 {
+		if cfg.EmbeddingCachePath != "" {
+			embeddingCache, err = embeddings.OpenDiskCache(cfg.EmbeddingCachePath)
+			if err != nil {
+				log.Fatalf("failed to open embedding cache: %v", err)
+			}
+			embeddingCache.MaxEntries = cfg.EmbeddingCacheDiskSize
+		}
+		embedder = embeddings.NewCachedEmbeddings(embedder, cfg.EmbeddingCacheSize, embeddingCache)
+	}
+
+	dimension, err := embeddings.Dimension(ctx, embedder, cfg.EmbeddingDimension)
+	if err != nil {
+		log.Fatalf("failed to determine embedding dimension: %v", err)
+	}
+	log.Printf("Using embedding model %s with dimension %d", embedder.GetModel(), dimension)
+	if _, maxTokens := textprocessor.ModelTokenizer(embedder.GetModel()); maxTokens == 0 && cfg.ChunkMaxTokens == 0 {
+		log.Printf("Unknown context size of embedding model %s, set CHUNK_MAX_TOKENS to limit the chunks", embedder.GetModel())
+	}
+
+	reranker, err := provider.NewReranker(cfg, llm)
+	if err != nil {
+		log.Fatalf("failed to configure reranker: %v", err)
+	}
+
+	database, err := provider.NewDatabase(ctx, cfg, dimension)
+	if err != nil {
+		log.Fatalf("failed to configure vector store: %v", err)
+	}
+
+	tasks, err := task.NewTracker(cfg.TasksDir)
+	if err != nil {
+		log.Fatalf("failed to open task tracker: %v", err)
+	}
+	tasks.Retention = time.Duration(cfg.TaskRetentionHours) * time.Hour
+	if err := tasks.Prune(); err != nil {
+		log.Fatalf("failed to prune finished tasks: %v", err)
+	}
+
+	journal, err := queue.OpenJournal(cfg.IngestQueuePath)
+	if err != nil {
+		log.Fatalf("failed to open ingestion journal: %v", err)
+	}
+
+	versions, err := history.NewStore(cfg.HistoryDir)
+	if err != nil {
+		log.Fatalf("failed to open document history: %v", err)
+	}
+
+	if cfg.ContextOrder != rag.OrderByScore && cfg.ContextOrder != rag.OrderByDocument {
+		log.Fatalf("invalid CONTEXT_ORDER %q, expected %q or %q", cfg.ContextOrder, rag.OrderByScore, rag.OrderByDocument)
+	}
+	contextOptions := rag.ContextOptions{
+		MaxChars: cfg.ContextMaxChars,
+		Order:    cfg.ContextOrder,
+	}
+
+	if cfg.HybridKeywordWeight < 0 || cfg.HybridKeywordWeight > 1 {
+		log.Fatalf("invalid HYBRID_KEYWORD_WEIGHT %v, expected a value between 0 and 1", cfg.HybridKeywordWeight)
+	}
+
+	var keywords *bm25.Index
+	if cfg.KeywordIndexPath != "" {
+		keywords, err = bm25.NewIndex(cfg.KeywordIndexPath)
+		if err != nil {
+			log.Fatalf("failed to open keyword index: %v", err)
+		}
+		log.Printf("Keyword index loaded with %d chunks", keywords.Len())
+	}
+
+	rerankOptions := rag.RerankOptions{
+		Candidates: cfg.RerankCandidates,
+		TopN:       cfg.RerankTopN,
+		MinScore:   cfg.RerankMinScore,
+	}
+
+	chunking, err := textprocessor.Resolve(models.Chunking{
+		Strategy:   cfg.ChunkStrategy,
+		Size:       cfg.ChunkSize,
+		Overlap:    cfg.ChunkOverlap,
+		Percentile: cfg.ChunkPercentile,
+	})
+	if err != nil {
+		log.Fatalf("invalid chunking configuration: %v", err)
+	}
+
+	if err := rag.ValidateMode(cfg.UploadMode); err != nil {
+		log.Fatalf("invalid UPLOAD_MODE: %v", err)
+	}
+
+	if cfg.MaxRequestBytes <= 0 {
+		log.Fatalf("invalid MAX_REQUEST_BYTES %d, expected a positive size", cfg.MaxRequestBytes)
+	}
+
+	fetcher := fetch.NewFetcher(time.Duration(cfg.FetchTimeout)*time.Second, int64(cfg.FetchMaxBytes))
+	fetcher.MaxSitemapURLs = cfg.SitemapMaxURLs
+	fetcher.AllowedNetworks, err = fetch.ParseNetworks(cfg.FetchAllowedNetworks)
+	if err != nil {
+		log.Fatalf("invalid FETCH_ALLOWED_NETWORKS: %v", err)
+	}
 
 	// Rag instance
-	rag := rag.NewRag(llm, embeddings, database)
+	rag := rag.NewRag(llm, embedder, database, tasks)
+	rag.Context = contextOptions
+	rag.MinScore = cfg.AskMinScore
+	rag.Keywords = keywords
+	rag.KeywordWeight = cfg.HybridKeywordWeight
+	rag.Reranker = reranker
+	rag.Rerank = rerankOptions
+	rag.Fetcher = fetcher
+	rag.Chunking = chunking
+	rag.MaxTokens = cfg.ChunkMaxTokens
+	rag.UploadMode = cfg.UploadMode
+	rag.History = versions
+	if err := rag.SyncKeywords(ctx); err != nil {
+		log.Fatalf("failed to sync keyword index: %v", err)
+	}
+	rag.StartWorkers(journal, cfg.IngestWorkers)
 
 	// Echo WebServer instance
 	e := echo.New()
 
+	// uploads are journaled before they are processed, larger bodies are refused upfront
+	e.Use(middleware.BodyLimit(fmt.Sprintf("%dB", cfg.MaxRequestBytes)))
+
 	// Wrapper for API
 	api.NewAPI(e, rag)
 
 	// Start Server
-	e.Logger.Fatal(e.Start(":4002"))
+	go func() {
+		if err := e.Start(":4002"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down server: %v", err)
+	}
+
+	// interrupted ingestion jobs stay in the journal and are resumed on the next start
+	rag.StopWorkers()
+	if err := journal.Close(); err != nil {
+		log.Printf("failed to close ingestion journal: %v", err)
+	}
+	if embeddingCache != nil {
+		if err := embeddingCache.Close(); err != nil {
+			log.Printf("failed to close embedding cache: %v", err)
+		}
+	}
+	if keywords != nil {
+		if err := keywords.Close(); err != nil {
+			log.Printf("failed to close keyword index: %v", err)
+		}
+	}
 }
@@ -3,6 +3,11 @@ package config
 import cfg "github.com/eschao/config"
 
 type Config struct {
+	// Providers
+	LLMProvider       string `env:"LLM_PROVIDER"`       // ollama | openai
+	EmbeddingProvider string `env:"EMBEDDING_PROVIDER"` // ollama | openai
+	VectorStore       string `env:"VECTOR_STORE"`       // milvus
+
 	// LLM
 	OpenAIAPIKey   string `env:"OPENAI_API_KEY"`
 	OpenAIEndpoint string `env:"OPENAI_ENDPOINT"`
@@ -14,20 +19,100 @@ type Config struct {
 	OpenAIEmbeddingAPIKey   string `env:"OPENAI_EMBEDDING_API_KEY"`
 	OpenAIEmbeddingEndpoint string `env:"OPENAI_EMBEDDING_ENDPOINT"`
 	OpenAIEmbeddingModel    string `env:"OPENAI_EMBEDDING_MODEL"`
+	OpenAIEmbeddingDims     int    `env:"OPENAI_EMBEDDING_DIMENSIONS"`
+	OpenAIEmbeddingBatch    int    `env:"OPENAI_EMBEDDING_BATCH_SIZE"`
 	OllamaEmbeddingEndpoint string `env:"OLLAMA_EMBEDDING_ENDPOINT"`
 	OllamaEmbeddingModel    string `env:"OLLAMA_EMBEDDING_MODEL"`
+	OllamaEmbeddingBatch    int    `env:"OLLAMA_EMBEDDING_BATCH_SIZE"`
+	EmbeddingDimension      int    `env:"EMBEDDING_DIMENSION"`       // 0 probes the model on startup
+	EmbeddingCacheSize      int    `env:"EMBEDDING_CACHE_SIZE"`      // Embeddings kept in memory, 0 disables the cache
+	EmbeddingCachePath      string `env:"EMBEDDING_CACHE_PATH"`      // Empty keeps the cache in memory only
+	EmbeddingCacheDiskSize  int    `env:"EMBEDDING_CACHE_DISK_SIZE"` // Embeddings kept on disk, 0 keeps them all
 
 	// Database
-	MilvusHost string `env:"MILVUS_HOST"`
+	MilvusHost            string `env:"MILVUS_HOST"`
+	MilvusDocumentsMetric string `env:"MILVUS_DOCUMENTS_METRIC"` // L2 | IP | COSINE
+	MilvusChunksMetric    string `env:"MILVUS_CHUNKS_METRIC"`    // L2 | IP | COSINE
+
+	// Retrieval
+	ContextMaxChars int     `env:"CONTEXT_MAX_CHARS"` // Character budget of the chunks sent to the LLM
+	ContextOrder    string  `env:"CONTEXT_ORDER"`     // score | document
+	AskMinScore     float32 `env:"ASK_MIN_SCORE"`     // Minimum similarity of the chunks used to answer, 0 keeps all
+
+	// Hybrid retrieval
+	KeywordIndexPath    string  `env:"KEYWORD_INDEX_PATH"`    // Empty disables the keyword index
+	HybridKeywordWeight float64 `env:"HYBRID_KEYWORD_WEIGHT"` // 0 is vector only, 1 keyword only
+
+	// Reranking
+	Reranker         string  `env:"RERANKER"` // none | llm | http
+	RerankEndpoint   string  `env:"RERANK_ENDPOINT"`
+	RerankAPIKey     string  `env:"RERANK_API_KEY"`
+	RerankModel      string  `env:"RERANK_MODEL"`
+	RerankCandidates int     `env:"RERANK_CANDIDATES"` // Chunks retrieved for the reranker
+	RerankTopN       int     `env:"RERANK_TOP_N"`      // Chunks kept after reranking
+	RerankMinScore   float32 `env:"RERANK_MIN_SCORE"`  // Chunks with a lower rerank score are dropped
+
+	// Tasks
+	TasksDir           string `env:"TASKS_DIR"`
+	TaskRetentionHours int    `env:"TASK_RETENTION_HOURS"` // Finished tasks older than this are removed, 0 keeps them
+
+	// Ingestion
+	MaxRequestBytes int    `env:"MAX_REQUEST_BYTES"` // Largest request body accepted
+	IngestWorkers   int    `env:"INGEST_WORKERS"`
+	IngestQueuePath string `env:"INGEST_QUEUE_PATH"`
+	UploadMode      string `env:"UPLOAD_MODE"` // skip | replace | new_version, for documents already uploaded
+	HistoryDir      string `env:"HISTORY_DIR"` // Versions of the documents, empty keeps them in memory only
+
+	// Chunking
+	ChunkStrategy   string  `env:"CHUNK_STRATEGY"` // sentence | fixed | recursive | markdown | token | semantic
+	ChunkSize       int     `env:"CHUNK_SIZE"`     // 0 is the strategy default
+	ChunkOverlap    int     `env:"CHUNK_OVERLAP"`
+	ChunkMaxTokens  int     `env:"CHUNK_MAX_TOKENS"` // 0 is the context size of the embedding model
+	ChunkPercentile float64 `env:"CHUNK_PERCENTILE"` // Breakpoint percentile of the semantic strategy, 0 is the default
+
+	// URL ingestion
+	FetchTimeout         int    `env:"FETCH_TIMEOUT"`          // Seconds allowed for each page download
+	FetchMaxBytes        int    `env:"FETCH_MAX_BYTES"`        // Largest page downloaded
+	SitemapMaxURLs       int    `env:"SITEMAP_MAX_URLS"`       // Most pages queued from a sitemap
+	FetchAllowedNetworks string `env:"FETCH_ALLOWED_NETWORKS"` // Comma-separated internal networks pages may be fetched from
 }
 
 func NewConfig() Config {
 	config := Config{
+		LLMProvider:             "ollama",
+		EmbeddingProvider:       "ollama",
+		VectorStore:             "milvus",
 		MilvusHost:              "localhost:19530",
+		MilvusDocumentsMetric:   "L2",
+		MilvusChunksMetric:      "L2",
 		OllamaEmbeddingEndpoint: "http://localhost:11434",
 		OllamaEmbeddingModel:    "bge-m3",
+		OllamaEmbeddingBatch:    32,
 		OllamaEndpoint:          "http://localhost:11434/api/chat",
 		OllamaModel:             "llama3.2:3b",
+		OpenAIEndpoint:          "https://api.openai.com/v1",
+		OpenAIEmbeddingEndpoint: "https://api.openai.com/v1",
+		OpenAIEmbeddingBatch:    64,
+		EmbeddingCacheSize:      10000,
+		EmbeddingCacheDiskSize:  100000,
+		ContextMaxChars:         12000,
+		ContextOrder:            "score",
+		KeywordIndexPath:        "data/keyword_index.db",
+		HybridKeywordWeight:     0.3,
+		Reranker:                "none",
+		RerankCandidates:        30,
+		RerankTopN:              10,
+		TasksDir:                "data/tasks",
+		TaskRetentionHours:      168,
+		MaxRequestBytes:         40 << 20,
+		IngestWorkers:           2,
+		IngestQueuePath:         "data/ingest.journal",
+		UploadMode:              "skip",
+		HistoryDir:              "data/history",
+		ChunkStrategy:           "sentence",
+		FetchTimeout:            30,
+		FetchMaxBytes:           10 << 20,
+		SitemapMaxURLs:          500,
 	}
 	cfg.ParseEnv(&config)
 	return config
@@ -1,20 +1,50 @@
 package database
 
-import "github.com/elchemista/easy_rag/internal/models"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
 
 // database interface
 
+// ErrNotFound is returned when no document has the requested ID
+var ErrNotFound = errors.New("document not found")
+
+// chunkLimiter is implemented by stores limiting the size of the chunk texts
+type chunkLimiter interface {
+	MaxChunkBytes() int
+}
+
+// MaxChunkBytes returns the largest chunk text the database stores, in bytes, 0 if it has no limit
+func MaxChunkBytes(db Database) int {
+	if l, ok := db.(chunkLimiter); ok {
+		return l.MaxChunkBytes()
+	}
+	return 0
+}
+
+// ChunkScanner is implemented by stores able to list every stored chunk
+type ChunkScanner interface {
+	CountChunks(ctx context.Context) (int, error)                                   // chunks of every version of the documents
+	ScanChunks(ctx context.Context, fn func(chunks []models.Embedding) error) error // fn is called page by page, the chunks are without their vector
+}
+
 // Database defines the interface for interacting with a database
 type Database interface {
-	SaveDocument(document models.Document) error        // the content will be chunked and saved
-	GetDocumentInfo(id string) (models.Document, error) // return the document with the given id without content
-	GetDocument(id string) (models.Document, error)     // return the document with the given id with content assembled
-	Search(vector [][]float32) ([]models.Embedding, error)
-	ListDocuments() ([]models.Document, error)
-	DeleteDocument(id string) error
-	SaveEmbeddings(embeddings []models.Embedding) error
-	// to implement	in future
-	// SearchByCategory(category []string) ([]Embedding, error)
-	// SearchByMetadata(metadata map[string]string) ([]Embedding, error)
-	// GetAllEmbeddingByDocumentID(documentID string) ([]Embedding, error)
+	SaveDocument(ctx context.Context, document models.Document) error                                           // the content will be chunked and saved
+	UpdateDocument(ctx context.Context, document models.Document) error                                         // replace the stored document, keeping its vector
+	GetDocumentInfo(ctx context.Context, id string) (models.Document, error)                                    // return the document with the given id without content
+	GetDocument(ctx context.Context, id string) (models.Document, error)                                        // return the document with the given id with the content of its latest version assembled
+	Search(ctx context.Context, vector [][]float32, topK int, filter models.Filter) ([]models.Embedding, error) // only chunks of documents matching the filter
+	ListDocuments(ctx context.Context) ([]models.Document, error)
+	FindDocuments(ctx context.Context, externalID, contentHash string) ([]models.Document, error) // documents with the external ID, or with the content hash if externalID is empty
+	DeleteDocument(ctx context.Context, id string) error
+	SaveEmbeddings(ctx context.Context, embeddings []models.Embedding) error
+	DeleteEmbeddings(ctx context.Context, ids []string) error
+	GetChunks(ctx context.Context, documentID string, from, to int64, asOf *time.Time) ([]models.Embedding, error) // chunks with an order in [from, to] of the version current at asOf, the latest if nil
+	GetEmbeddings(ctx context.Context, documentID string) ([]models.Embedding, error)                              // every chunk of the latest version, with its vector
+	GetChunksByID(ctx context.Context, ids []string, filter models.Filter) ([]models.Embedding, error)             // chunks with the given IDs matching the filter, without their vector
 }
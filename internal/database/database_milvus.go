@@ -3,8 +3,10 @@ package database
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/elchemista/easy_rag/internal/models"
 	"github.com/elchemista/easy_rag/internal/pkg/database/milvus"
@@ -16,37 +18,47 @@ type Milvus struct {
 	Client *milvus.Client
 }
 
-func NewMilvus(host string) *Milvus {
+// NewMilvus connects to Milvus, the vector indexes of the "documents" and "chunks"
+// collections use the given metrics (L2, IP or COSINE)
+func NewMilvus(ctx context.Context, host string, dim int, documentsMetric, chunksMetric string) (*Milvus, error) {
+	docMetric, err := milvus.ParseMetric(documentsMetric)
+	if err != nil {
+		return nil, fmt.Errorf("invalid documents metric: %w", err)
+	}
+	chunkMetric, err := milvus.ParseMetric(chunksMetric)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chunks metric: %w", err)
+	}
 
-	milviusClient, err := milvus.NewClient(host)
+	milviusClient, err := milvus.NewClient(ctx, host, dim, docMetric, chunkMetric)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to connect to milvus at %s: %w", host, err)
 	}
 
 	return &Milvus{
 		Host:   host,
 		Client: milviusClient,
-	}
+	}, nil
 }
 
-func (m *Milvus) SaveDocument(document models.Document) error {
-	// for now lets use context background
-	ctx := context.Background()
+// MaxChunkBytes returns the max length of the TextChunk field
+func (m *Milvus) MaxChunkBytes() int {
+	return milvus.TextChunkMaxLength
+}
 
+func (m *Milvus) SaveDocument(ctx context.Context, document models.Document) error {
 	return m.Client.InsertDocuments(ctx, []models.Document{document})
 }
 
-func (m *Milvus) SaveEmbeddings(embeddings []models.Embedding) error {
-	ctx := context.Background()
+func (m *Milvus) SaveEmbeddings(ctx context.Context, embeddings []models.Embedding) error {
 	return m.Client.InsertEmbeddings(ctx, embeddings)
 }
 
-func (m *Milvus) GetDocumentInfo(id string) (models.Document, error) {
-	ctx := context.Background()
+func (m *Milvus) GetDocumentInfo(ctx context.Context, id string) (models.Document, error) {
 	doc, err := m.Client.GetDocumentByID(ctx, id)
 
 	if err != nil {
-		return models.Document{}, err
+		return models.Document{}, notFound(id, err)
 	}
 
 	if len(doc) == 0 {
@@ -60,14 +72,17 @@ func (m *Milvus) GetDocumentInfo(id string) (models.Document, error) {
 		EmbeddingModel: doc["EmbeddingModel"].(string),
 		Summary:        doc["Summary"].(string),
 		Metadata:       doc["Metadata"].(map[string]string),
+		ExternalID:     doc["ExternalID"].(string),
+		ContentHash:    doc["ContentHash"].(string),
+		Version:        doc["Version"].(int64),
+		UpdatedAt:      doc["UpdatedAt"].(int64),
 	}, nil
 }
 
-func (m *Milvus) GetDocument(id string) (models.Document, error) {
-	ctx := context.Background()
+func (m *Milvus) GetDocument(ctx context.Context, id string) (models.Document, error) {
 	doc, err := m.Client.GetDocumentByID(ctx, id)
 	if err != nil {
-		return models.Document{}, err
+		return models.Document{}, notFound(id, err)
 	}
 
 	embeds, err := m.Client.GetAllEmbeddingByDocID(ctx, id)
@@ -101,12 +116,15 @@ func (m *Milvus) GetDocument(id string) (models.Document, error) {
 		EmbeddingModel: doc["EmbeddingModel"].(string),
 		Summary:        doc["Summary"].(string),
 		Metadata:       doc["Metadata"].(map[string]string),
+		ExternalID:     doc["ExternalID"].(string),
+		ContentHash:    doc["ContentHash"].(string),
+		Version:        doc["Version"].(int64),
+		UpdatedAt:      doc["UpdatedAt"].(int64),
 	}, nil
 }
 
-func (m *Milvus) Search(vector [][]float32) ([]models.Embedding, error) {
-	ctx := context.Background()
-	results, err := m.Client.Search(ctx, vector, 10)
+func (m *Milvus) Search(ctx context.Context, vector [][]float32, topK int, filter models.Filter) ([]models.Embedding, error) {
+	results, err := m.Client.Search(ctx, vector, topK, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -114,9 +132,24 @@ func (m *Milvus) Search(vector [][]float32) ([]models.Embedding, error) {
 	return results, nil
 }
 
-func (m *Milvus) ListDocuments() ([]models.Document, error) {
-	ctx := context.Background()
+func (m *Milvus) GetChunks(ctx context.Context, documentID string, from, to int64, asOf *time.Time) ([]models.Embedding, error) {
+	return m.Client.GetEmbeddingsByOrder(ctx, documentID, from, to, asOf)
+}
+
+func (m *Milvus) GetChunksByID(ctx context.Context, ids []string, filter models.Filter) ([]models.Embedding, error) {
+	return m.Client.GetEmbeddingsByID(ctx, ids, filter)
+}
+
+func (m *Milvus) CountChunks(ctx context.Context) (int, error) {
+	count, err := m.Client.CountEmbeddings(ctx)
+	return int(count), err
+}
+
+func (m *Milvus) ScanChunks(ctx context.Context, fn func(chunks []models.Embedding) error) error {
+	return m.Client.ScanEmbeddings(ctx, fn)
+}
 
+func (m *Milvus) ListDocuments(ctx context.Context) ([]models.Document, error) {
 	docs, err := m.Client.GetAllDocuments(ctx)
 
 	if err != nil {
@@ -126,8 +159,29 @@ func (m *Milvus) ListDocuments() ([]models.Document, error) {
 	return docs, nil
 }
 
-func (m *Milvus) DeleteDocument(id string) error {
-	ctx := context.Background()
+// UpdateDocument replaces the stored document, keeping its vector
+func (m *Milvus) UpdateDocument(ctx context.Context, document models.Document) error {
+	vector, err := m.Client.GetDocumentVector(ctx, document.ID)
+	if err != nil {
+		return notFound(document.ID, err)
+	}
+	document.Vector = vector
+	return m.Client.InsertDocuments(ctx, []models.Document{document})
+}
+
+func (m *Milvus) GetEmbeddings(ctx context.Context, documentID string) ([]models.Embedding, error) {
+	return m.Client.GetDocumentEmbeddings(ctx, documentID)
+}
+
+func (m *Milvus) FindDocuments(ctx context.Context, externalID, contentHash string) ([]models.Document, error) {
+	return m.Client.FindDocuments(ctx, externalID, contentHash)
+}
+
+func (m *Milvus) DeleteEmbeddings(ctx context.Context, ids []string) error {
+	return m.Client.DeleteEmbeddingsByID(ctx, ids)
+}
+
+func (m *Milvus) DeleteDocument(ctx context.Context, id string) error {
 	err := m.Client.DeleteDocument(ctx, id)
 	if err != nil {
 		return err
@@ -140,3 +194,11 @@ func (m *Milvus) DeleteDocument(id string) error {
 
 	return nil
 }
+
+// notFound converts the not found error of the client into ErrNotFound
+func notFound(id string, err error) error {
+	if errors.Is(err, milvus.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package embeddings
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultCacheSize is the number of embeddings kept in memory by default
+const DefaultCacheSize = 10000
+
+// CacheStats are the counters of an embedding cache
+type CacheStats struct {
+	Hits    int64 `json:"hits"`    // Texts served from the cache, memory or disk
+	Misses  int64 `json:"misses"`  // Texts sent to the embedding service
+	Entries int   `json:"entries"` // Embeddings held in memory
+}
+
+// CachedEmbeddings wraps an EmbeddingsService with a cache of its embeddings keyed by the
+// scope of the service (model, endpoint and requested dimension) and the SHA-256 of the text: an in-memory LRU, backed by an optional disk cache
+// that survives restarts. The cached vectors are shared, callers must not modify them.
+type CachedEmbeddings struct {
+	Service EmbeddingsService
+	Disk    *DiskCache // nil keeps the embeddings in memory only
+
+	mu      sync.Mutex
+	size    int
+	order   *list.List               // most recently used first
+	entries map[string]*list.Element // key to element of order
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// cacheEntry is an element of the LRU list
+type cacheEntry struct {
+	key    string
+	vector []float32
+}
+
+// NewCachedEmbeddings returns the service with a cache of up to size embeddings in memory
+func NewCachedEmbeddings(service EmbeddingsService, size int, disk *DiskCache) *CachedEmbeddings {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &CachedEmbeddings{
+		Service: service,
+		Disk:    disk,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Vectorize returns the embedding of the text, from the cache when possible
+func (c *CachedEmbeddings) Vectorize(ctx context.Context, text string) ([][]float32, error) {
+	return c.VectorizeBatch(ctx, []string{text})
+}
+
+// VectorizeBatch returns one embedding per text, only the texts missing from the cache are
+// sent to the service, each one once
+func (c *CachedEmbeddings) VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	scope := cacheScope(c.Service)
+	vectors := make([][]float32, len(texts))
+
+	// texts to embed, with the positions they fill
+	var missing []string
+	positions := make(map[string][]int)
+	for i, text := range texts {
+		key := cacheKey(scope, text)
+		if vector, ok := c.get(key); ok {
+			vectors[i] = vector
+			c.hits.Add(1)
+			continue
+		}
+		if _, ok := positions[key]; !ok {
+			missing = append(missing, text)
+		}
+		positions[key] = append(positions[key], i)
+	}
+
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+	c.misses.Add(int64(len(missing)))
+
+	embedded, err := c.Service.VectorizeBatch(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(missing), len(embedded))
+	}
+
+	keys := make([]string, len(missing))
+	for i, text := range missing {
+		keys[i] = cacheKey(scope, text)
+		c.put(keys[i], embedded[i])
+		for _, position := range positions[keys[i]] {
+			vectors[position] = embedded[i]
+		}
+	}
+	if c.Disk != nil {
+		if err := c.Disk.Put(keys, embedded); err != nil {
+			log.Printf("Embedding cache: failed to write %d embeddings to disk: %v", len(keys), err)
+		}
+	}
+
+	return vectors, nil
+}
+
+func (c *CachedEmbeddings) GetModel() string {
+	return c.Service.GetModel()
+}
+
+// Dimension returns the dimension of the wrapped service, 0 if it doesn't know it
+func (c *CachedEmbeddings) Dimension() int {
+	if d, ok := c.Service.(dimensioner); ok {
+		return d.Dimension()
+	}
+	return 0
+}
+
+// Stats returns the hit and miss counters and the number of embeddings in memory
+func (c *CachedEmbeddings) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries}
+}
+
+// get returns the embedding of the key from memory, or from disk
+func (c *CachedEmbeddings) get(key string) ([]float32, bool) {
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.mu.Unlock()
+		return element.Value.(*cacheEntry).vector, true
+	}
+	c.mu.Unlock()
+
+	if c.Disk == nil {
+		return nil, false
+	}
+	vector, ok, err := c.Disk.Get(key)
+	if err != nil {
+		log.Printf("Embedding cache: failed to read from disk: %v", err)
+		return nil, false
+	}
+	if ok {
+		c.put(key, vector)
+	}
+	return vector, ok
+}
+
+// put adds the embedding to memory, evicting the least recently used one when full
+func (c *CachedEmbeddings) put(key string, vector []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).vector = vector
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, vector: vector})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// cacheScoper is implemented by services whose embeddings depend on more than the model name,
+// like the endpoint serving the model or the requested output dimension
+type cacheScoper interface {
+	CacheScope() string
+}
+
+// cacheScope returns what the embeddings of the service depend on besides the text, the model
+// for services that don't tell
+func cacheScope(service EmbeddingsService) string {
+	if s, ok := service.(cacheScoper); ok {
+		return s.CacheScope()
+	}
+	return service.GetModel()
+}
+
+// cacheKey identifies the embedding of a text in a scope
+func cacheKey(scope string, text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return scope + "/" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,174 @@
+package embeddings
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// cacheBucket is the bbolt bucket of the embeddings
+	cacheBucket = []byte("embeddings")
+	// orderBucket lists the keys of the embeddings in the order they were written, keyed by
+	// a sequence number
+	orderBucket = []byte("order")
+)
+
+// DiskCache stores embeddings in a bbolt file, keyed like CachedEmbeddings. Beyond MaxEntries
+// the oldest embeddings are evicted, the file keeps its size and reuses their space.
+type DiskCache struct {
+	db      *bolt.DB
+	mu      sync.Mutex
+	entries int // Number of stored embeddings
+
+	MaxEntries int // Embeddings kept, 0 keeps them all
+}
+
+// OpenDiskCache opens or creates the cache file
+func OpenDiskCache(path string) (*DiskCache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create embedding cache directory: %w", err)
+	}
+
+	// another process holding the file makes Open fail after the timeout instead of blocking
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedding cache %s: %w", path, err)
+	}
+
+	d := &DiskCache{db: db}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(cacheBucket)
+		if err != nil {
+			return err
+		}
+		order, err := tx.CreateBucketIfNotExists(orderBucket)
+		if err != nil {
+			return err
+		}
+
+		// a file written before eviction has no order, its embeddings are the oldest
+		if order.Stats().KeyN == 0 {
+			err := bucket.ForEach(func(key, _ []byte) error {
+				return appendOrder(order, key)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		d.entries = bucket.Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize embedding cache %s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+// Get returns the embedding stored under the key
+func (d *DiskCache) Get(key string) ([]float32, bool, error) {
+	var vector []float32
+	err := d.db.View(func(tx *bolt.Tx) error {
+		// the value is only valid during the transaction, decoding copies it
+		if value := tx.Bucket(cacheBucket).Get([]byte(key)); value != nil {
+			vector = decodeVector(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return vector, vector != nil, nil
+}
+
+// Put stores the embeddings under their keys in a single transaction, evicting the oldest
+// ones beyond MaxEntries
+func (d *DiskCache) Put(keys []string, vectors [][]float32) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := d.entries
+	err := d.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(cacheBucket)
+		order := tx.Bucket(orderBucket)
+		for i, key := range keys {
+			if bucket.Get([]byte(key)) == nil {
+				if err := appendOrder(order, []byte(key)); err != nil {
+					return err
+				}
+				entries++
+			}
+			if err := bucket.Put([]byte(key), encodeVector(vectors[i])); err != nil {
+				return err
+			}
+		}
+
+		if d.MaxEntries <= 0 || entries <= d.MaxEntries {
+			return nil
+		}
+
+		// the keys are copied, deleting changes the pages they point to
+		var evicted [][2][]byte
+		cursor := order.Cursor()
+		for seq, key := cursor.First(); seq != nil && entries-len(evicted) > d.MaxEntries; seq, key = cursor.Next() {
+			evicted = append(evicted, [2][]byte{append([]byte(nil), seq...), append([]byte(nil), key...)})
+		}
+		for _, entry := range evicted {
+			if err := order.Delete(entry[0]); err != nil {
+				return err
+			}
+			if err := bucket.Delete(entry[1]); err != nil {
+				return err
+			}
+		}
+		entries -= len(evicted)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.entries = entries
+	return nil
+}
+
+// appendOrder records the key as the last written one
+func appendOrder(order *bolt.Bucket, key []byte) error {
+	seq, err := order.NextSequence()
+	if err != nil {
+		return err
+	}
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], seq)
+	return order.Put(id[:], key)
+}
+
+// Close closes the cache file
+func (d *DiskCache) Close() error {
+	return d.db.Close()
+}
+
+// encodeVector encodes the vector as little-endian float32s
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(value))
+	}
+	return data
+}
+
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
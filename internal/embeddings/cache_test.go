@@ -0,0 +1,218 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var _ EmbeddingsService = (*CachedEmbeddings)(nil)
+
+// countingEmbeddings embeds a text as its length and records the texts it receives
+type countingEmbeddings struct {
+	model string
+	texts []string
+}
+
+func (e *countingEmbeddings) Vectorize(ctx context.Context, text string) ([][]float32, error) {
+	return e.VectorizeBatch(ctx, []string{text})
+}
+
+func (e *countingEmbeddings) VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 1}
+	}
+	return vectors, nil
+}
+
+func (e *countingEmbeddings) GetModel() string {
+	return e.model
+}
+
+func TestCachedEmbeddings(t *testing.T) {
+	service := &countingEmbeddings{model: "bge-m3"}
+	cache := NewCachedEmbeddings(service, 10, nil)
+	ctx := context.Background()
+
+	vectors, err := cache.VectorizeBatch(ctx, []string{"a", "bb", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]float32{{1, 1}, {2, 1}, {1, 1}}
+	if !reflect.DeepEqual(vectors, want) {
+		t.Errorf("vectors = %v, want %v", vectors, want)
+	}
+
+	vectors, err = cache.VectorizeBatch(ctx, []string{"bb", "ccc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = [][]float32{{2, 1}, {3, 1}}
+	if !reflect.DeepEqual(vectors, want) {
+		t.Errorf("vectors = %v, want %v", vectors, want)
+	}
+
+	if want := []string{"a", "bb", "ccc"}; !reflect.DeepEqual(service.texts, want) {
+		t.Errorf("embedded texts = %v, want %v", service.texts, want)
+	}
+	if stats, want := cache.Stats(), (CacheStats{Hits: 1, Misses: 3, Entries: 3}); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestCachedEmbeddingsKeyedByModel(t *testing.T) {
+	service := &countingEmbeddings{model: "bge-m3"}
+	cache := NewCachedEmbeddings(service, 10, nil)
+	ctx := context.Background()
+
+	if _, err := cache.Vectorize(ctx, "text"); err != nil {
+		t.Fatal(err)
+	}
+	service.model = "nomic-embed-text"
+	if _, err := cache.Vectorize(ctx, "text"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(service.texts) != 2 {
+		t.Errorf("embedded %d texts, want 2", len(service.texts))
+	}
+}
+
+// dimensionedEmbeddings is a countingEmbeddings scoped by its requested dimension
+type dimensionedEmbeddings struct {
+	countingEmbeddings
+	dimension int
+}
+
+func (e *dimensionedEmbeddings) CacheScope() string {
+	return fmt.Sprintf("%s/%d", e.model, e.dimension)
+}
+
+func TestCachedEmbeddingsKeyedByScope(t *testing.T) {
+	service := &dimensionedEmbeddings{countingEmbeddings: countingEmbeddings{model: "text-embedding-3-small"}, dimension: 256}
+	cache := NewCachedEmbeddings(service, 10, nil)
+	ctx := context.Background()
+
+	for _, dimension := range []int{256, 512, 256} {
+		service.dimension = dimension
+		if _, err := cache.Vectorize(ctx, "text"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(service.texts) != 2 {
+		t.Errorf("embedded %d texts, want one per dimension", len(service.texts))
+	}
+
+	scopes := map[string]bool{}
+	for _, service := range []EmbeddingsService{
+		&OpenAIEmbeddings{Endpoint: "https://api.openai.com/v1", Model: "text-embedding-3-small"},
+		&OpenAIEmbeddings{Endpoint: "https://api.openai.com/v1", Model: "text-embedding-3-small", Dimensions: 256},
+		&OpenAIEmbeddings{Endpoint: "http://localhost:8080/v1", Model: "text-embedding-3-small"},
+		NewOllamaEmbeddings("http://localhost:11434", "text-embedding-3-small"),
+	} {
+		scopes[cacheScope(service)] = true
+	}
+	if len(scopes) != 4 {
+		t.Errorf("cache scopes = %v, want one per endpoint and dimension", scopes)
+	}
+}
+
+func TestCachedEmbeddingsEviction(t *testing.T) {
+	service := &countingEmbeddings{model: "bge-m3"}
+	cache := NewCachedEmbeddings(service, 2, nil)
+	ctx := context.Background()
+
+	// "a" is used again before "c" is added, so "b" is evicted
+	for _, text := range []string{"a", "b", "a", "c", "a", "b"} {
+		if _, err := cache.Vectorize(ctx, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"a", "b", "c", "b"}; !reflect.DeepEqual(service.texts, want) {
+		t.Errorf("embedded texts = %v, want %v", service.texts, want)
+	}
+	if stats := cache.Stats(); stats.Entries != 2 {
+		t.Errorf("entries = %d, want 2", stats.Entries)
+	}
+}
+
+func TestCachedEmbeddingsDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "embeddings.db")
+	ctx := context.Background()
+
+	disk, err := OpenDiskCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := &countingEmbeddings{model: "bge-m3"}
+	if _, err := NewCachedEmbeddings(service, 10, disk).VectorizeBatch(ctx, []string{"a", "bb"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := disk.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new cache on the same file, like after a restart
+	disk, err = OpenDiskCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer disk.Close()
+	service = &countingEmbeddings{model: "bge-m3"}
+	cache := NewCachedEmbeddings(service, 10, disk)
+
+	vectors, err := cache.VectorizeBatch(ctx, []string{"bb", "a", "ccc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float32{{2, 1}, {1, 1}, {3, 1}}; !reflect.DeepEqual(vectors, want) {
+		t.Errorf("vectors = %v, want %v", vectors, want)
+	}
+	if want := []string{"ccc"}; !reflect.DeepEqual(service.texts, want) {
+		t.Errorf("embedded texts = %v, want %v", service.texts, want)
+	}
+	if stats, want := cache.Stats(), (CacheStats{Hits: 2, Misses: 1, Entries: 3}); stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestDiskCacheEvictsOldest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.db")
+
+	disk, err := OpenDiskCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk.MaxEntries = 2
+	if err := disk.Put([]string{"a", "b"}, [][]float32{{1}, {2}}); err != nil {
+		t.Fatal(err)
+	}
+	// a key written again keeps its place
+	if err := disk.Put([]string{"a", "c"}, [][]float32{{1}, {3}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := disk.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	disk, err = OpenDiskCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer disk.Close()
+	disk.MaxEntries = 2
+
+	for key, want := range map[string]bool{"a": false, "b": true, "c": true} {
+		if _, ok, err := disk.Get(key); err != nil || ok != want {
+			t.Errorf("Get(%s) = %v, %v, want %v", key, ok, err, want)
+		}
+	}
+	if disk.entries != 2 {
+		t.Errorf("entries = %d, want 2", disk.entries)
+	}
+}
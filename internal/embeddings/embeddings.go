@@ -1,8 +1,43 @@
 package embeddings
 
+import (
+	"context"
+	"fmt"
+)
+
 // implement embeddings interface
 type EmbeddingsService interface {
 	// generate embedding from text
-	Vectorize(text string) ([][]float32, error)
+	Vectorize(ctx context.Context, text string) ([][]float32, error)
+	// generate one embedding per text, in the order of the texts, batching the requests
+	VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error)
 	GetModel() string
 }
+
+// dimensioner is implemented by services that know their output dimension
+type dimensioner interface {
+	Dimension() int
+}
+
+// Dimension returns the dimension of the vectors produced by the service.
+// A declared dimension (> 0) is used as is, otherwise the service is asked for it
+// or probed with a sample text.
+func Dimension(ctx context.Context, service EmbeddingsService, declared int) (int, error) {
+	if declared > 0 {
+		return declared, nil
+	}
+
+	if d, ok := service.(dimensioner); ok && d.Dimension() > 0 {
+		return d.Dimension(), nil
+	}
+
+	vectors, err := service.Vectorize(ctx, "dimension probe")
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe embedding model %s: %w", service.GetModel(), err)
+	}
+	if len(vectors) == 0 || len(vectors[0]) == 0 {
+		return 0, fmt.Errorf("embedding model %s returned an empty vector", service.GetModel())
+	}
+
+	return len(vectors[0]), nil
+}
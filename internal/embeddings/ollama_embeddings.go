@@ -2,30 +2,73 @@ package embeddings
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
+// defaultOllamaBatchSize is the number of inputs sent per request when BatchSize is not set
+const defaultOllamaBatchSize = 32
+
 type OllamaEmbeddings struct {
-	Endpoint string
-	Model    string
+	Endpoint  string
+	Model     string
+	BatchSize int // Maximum number of inputs per request
 }
 
 func NewOllamaEmbeddings(endpoint string, model string) *OllamaEmbeddings {
 	return &OllamaEmbeddings{
-		Endpoint: endpoint,
-		Model:    model,
+		Endpoint:  endpoint,
+		Model:     model,
+		BatchSize: defaultOllamaBatchSize,
 	}
 }
 
 // Vectorize generates an embedding for the provided text
-func (o *OllamaEmbeddings) Vectorize(text string) ([][]float32, error) {
+func (o *OllamaEmbeddings) Vectorize(ctx context.Context, text string) ([][]float32, error) {
+	return o.VectorizeBatch(ctx, []string{text})
+}
+
+// VectorizeBatch generates one embedding per text, sending up to BatchSize texts per
+// /api/embed request. The embeddings are returned in the order of the texts.
+func (o *OllamaEmbeddings) VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	batchSize := o.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultOllamaBatchSize
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+
+		batch, err := o.embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	return vectors, nil
+}
+
+func (o *OllamaEmbeddings) GetModel() string {
+	return o.Model
+}
+
+// CacheScope keys the cached embeddings by endpoint and model
+func (o *OllamaEmbeddings) CacheScope() string {
+	return fmt.Sprintf("ollama:%s/%s", strings.TrimRight(o.Endpoint, "/"), o.Model)
+}
+
+// embed sends a single /api/embed request for the given texts
+func (o *OllamaEmbeddings) embed(ctx context.Context, texts []string) ([][]float32, error) {
 	// Define the request payload
-	payload := map[string]string{
+	payload := map[string]interface{}{
 		"model": o.Model,
-		"input": text,
+		"input": texts,
 	}
 
 	// Convert the payload to JSON
@@ -36,7 +79,7 @@ func (o *OllamaEmbeddings) Vectorize(text string) ([][]float32, error) {
 
 	// Create the HTTP request
 	url := fmt.Sprintf("%s/api/embed", o.Endpoint)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -62,7 +105,7 @@ func (o *OllamaEmbeddings) Vectorize(text string) ([][]float32, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	// Assuming the response JSON contains an "embedding" field with a float32 array
+	// The response contains one embedding per input, in the order of the inputs
 	var response struct {
 		Embeddings [][]float32 `json:"embeddings"`
 	}
@@ -70,9 +113,9 @@ func (o *OllamaEmbeddings) Vectorize(text string) ([][]float32, error) {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	return response.Embeddings, nil
-}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Embeddings))
+	}
 
-func (o *OllamaEmbeddings) GetModel() string {
-	return o.Model
+	return response.Embeddings, nil
 }
@@ -0,0 +1,85 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var _ EmbeddingsService = (*OllamaEmbeddings)(nil)
+
+func TestOllamaEmbeddingsVectorizeBatch(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("path = %s, want /api/embed", r.URL.Path)
+		}
+
+		var request struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Model != "bge-m3" {
+			t.Errorf("model = %s, want bge-m3", request.Model)
+		}
+
+		embeddings := make([]string, len(request.Input))
+		for i, input := range request.Input {
+			var n int
+			fmt.Sscanf(input, "text %d", &n)
+			embeddings[i] = fmt.Sprintf("[%d,0]", n)
+		}
+		fmt.Fprintf(w, `{"model":"bge-m3","embeddings":[%s]}`, strings.Join(embeddings, ","))
+	}))
+	defer server.Close()
+
+	o := NewOllamaEmbeddings(server.URL, "bge-m3")
+	o.BatchSize = 2
+
+	texts := []string{"text 0", "text 1", "text 2", "text 3", "text 4"}
+	vectors, err := o.VectorizeBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("VectorizeBatch() error = %v", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors, want %d", len(vectors), len(texts))
+	}
+	for i, vector := range vectors {
+		if vector[0] != float32(i) {
+			t.Errorf("vectors[%d] = %v, want the embedding of text %d", i, vector, i)
+		}
+	}
+
+	// a single text is sent as a batch of one
+	vector, err := o.Vectorize(context.Background(), "text 7")
+	if err != nil {
+		t.Fatalf("Vectorize() error = %v", err)
+	}
+	if len(vector) != 1 || vector[0][0] != 7 {
+		t.Errorf("Vectorize() = %v, want one embedding", vector)
+	}
+}
+
+func TestOllamaEmbeddingsCountMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"embeddings":[[1,0]]}`)
+	}))
+	defer server.Close()
+
+	_, err := NewOllamaEmbeddings(server.URL, "bge-m3").VectorizeBatch(context.Background(), []string{"a", "b"})
+	if err == nil || !strings.Contains(err.Error(), "expected 2 embeddings, got 1") {
+		t.Errorf("VectorizeBatch() error = %v, want a count mismatch", err)
+	}
+}
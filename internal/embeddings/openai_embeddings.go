@@ -1,23 +1,172 @@
 package embeddings
 
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// defaultOpenAIBatchSize is the number of inputs sent per request when BatchSize is not set
+const defaultOpenAIBatchSize = 64
+
+// OpenAIEmbeddings implements EmbeddingsService for any OpenAI-compatible /v1/embeddings API
 type OpenAIEmbeddings struct {
-	APIKey   string
-	Endpoint string
-	Model    string
+	APIKey     string
+	Endpoint   string // Base URL, e.g. https://api.openai.com/v1
+	Model      string
+	Dimensions int // Requested output dimension, 0 keeps the model default
+	BatchSize  int // Maximum number of inputs per request
+
+	dimension atomic.Int64 // Dimension observed in the last response
 }
 
 func NewOpenAIEmbeddings(apiKey string, endpoint string, model string) *OpenAIEmbeddings {
 	return &OpenAIEmbeddings{
-		APIKey:   apiKey,
-		Endpoint: endpoint,
-		Model:    model,
+		APIKey:    apiKey,
+		Endpoint:  endpoint,
+		Model:     model,
+		BatchSize: defaultOpenAIBatchSize,
 	}
 }
 
-func (o *OpenAIEmbeddings) Vectorize(text string) ([]float32, error) {
-	return nil, nil
+// Vectorize generates an embedding for the provided text
+func (o *OpenAIEmbeddings) Vectorize(ctx context.Context, text string) ([][]float32, error) {
+	return o.VectorizeBatch(ctx, []string{text})
+}
+
+// VectorizeBatch generates one embedding per text, sending up to BatchSize texts per request.
+// The embeddings are returned in the order of the texts.
+func (o *OpenAIEmbeddings) VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	batchSize := o.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultOpenAIBatchSize
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+
+		batch, err := o.embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	return vectors, nil
+}
+
+// Dimension returns the dimension of the embeddings: the requested one if set,
+// otherwise the one observed in the last response (0 before the first call)
+func (o *OpenAIEmbeddings) Dimension() int {
+	if o.Dimensions > 0 {
+		return o.Dimensions
+	}
+	return int(o.dimension.Load())
 }
 
 func (o *OpenAIEmbeddings) GetModel() string {
 	return o.Model
 }
+
+// CacheScope keys the cached embeddings by endpoint, model and requested dimension, the
+// same model name may be served differently elsewhere or truncated to another dimension
+func (o *OpenAIEmbeddings) CacheScope() string {
+	return fmt.Sprintf("openai:%s/%s/%d", strings.TrimRight(o.Endpoint, "/"), o.Model, o.Dimensions)
+}
+
+// embed sends a single /embeddings request for the given texts
+func (o *OpenAIEmbeddings) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	payload := map[string]interface{}{
+		"model":           o.Model,
+		"input":           texts,
+		"encoding_format": "float",
+	}
+	if o.Dimensions > 0 {
+		payload["dimensions"] = o.Dimensions
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url(), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-200 response (%d): %s", resp.StatusCode, errorMessage(body))
+	}
+
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(response.Data))
+	}
+
+	// the API doesn't guarantee the order of the data, the index does
+	sort.Slice(response.Data, func(i, j int) bool {
+		return response.Data[i].Index < response.Data[j].Index
+	})
+
+	vectors := make([][]float32, len(response.Data))
+	for i, data := range response.Data {
+		vectors[i] = data.Embedding
+	}
+	o.dimension.Store(int64(len(vectors[0])))
+
+	return vectors, nil
+}
+
+// url returns the embeddings URL, accepting either a base URL or the full path
+func (o *OpenAIEmbeddings) url() string {
+	endpoint := strings.TrimRight(o.Endpoint, "/")
+	if strings.HasSuffix(endpoint, "/embeddings") {
+		return endpoint
+	}
+	return endpoint + "/embeddings"
+}
+
+// errorMessage extracts the message of an OpenAI error body, falling back to the raw body
+func errorMessage(body []byte) string {
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err == nil && response.Error.Message != "" {
+		return response.Error.Message
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,83 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var _ EmbeddingsService = (*OpenAIEmbeddings)(nil)
+
+func TestOpenAIEmbeddingsVectorizeBatch(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path = %s, want /v1/embeddings", r.URL.Path)
+		}
+
+		var request struct {
+			Model      string   `json:"model"`
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Dimensions != 3 {
+			t.Errorf("dimensions = %d, want 3", request.Dimensions)
+		}
+
+		// answer in reverse order, the client must sort by index
+		data := make([]string, 0, len(request.Input))
+		for i := len(request.Input) - 1; i >= 0; i-- {
+			var n int
+			fmt.Sscanf(request.Input[i], "text %d", &n)
+			data = append(data, fmt.Sprintf(`{"index":%d,"embedding":[%d,0,0]}`, i, n))
+		}
+		fmt.Fprintf(w, `{"data":[%s]}`, strings.Join(data, ","))
+	}))
+	defer server.Close()
+
+	o := NewOpenAIEmbeddings("secret", server.URL+"/v1", "text-embedding-3-small")
+	o.Dimensions = 3
+	o.BatchSize = 2
+
+	texts := []string{"text 0", "text 1", "text 2", "text 3", "text 4"}
+	vectors, err := o.VectorizeBatch(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("VectorizeBatch() error = %v", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+	if len(vectors) != len(texts) {
+		t.Fatalf("got %d vectors, want %d", len(vectors), len(texts))
+	}
+	for i, vector := range vectors {
+		if vector[0] != float32(i) {
+			t.Errorf("vector %d = %v, want first value %d", i, vector, i)
+		}
+	}
+	if o.Dimension() != 3 {
+		t.Errorf("Dimension() = %d, want 3", o.Dimension())
+	}
+}
+
+func TestOpenAIEmbeddingsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Incorrect API key provided"}}`))
+	}))
+	defer server.Close()
+
+	_, err := NewOpenAIEmbeddings("bad", server.URL, "model").Vectorize(context.Background(), "text")
+	if err == nil || !strings.Contains(err.Error(), "Incorrect API key provided") {
+		t.Errorf("Vectorize() error = %v, want the API error message", err)
+	}
+}
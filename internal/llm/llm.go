@@ -1,8 +1,18 @@
 package llm
 
+import "context"
+
 // implement llm interface
 type LLMService interface {
 	// generate text from prompt
-	Generate(prompt string) (string, error)
+	Generate(ctx context.Context, prompt string) (string, error)
 	GetModel() string
 }
+
+// StreamingLLMService is implemented by LLMs able to stream the generated text
+type StreamingLLMService interface {
+	LLMService
+	// GenerateStream calls onDelta with each piece of generated text as it arrives
+	// and returns the full text. An error returned by onDelta stops the generation.
+	GenerateStream(ctx context.Context, prompt string, onDelta func(delta string) error) (string, error)
+}
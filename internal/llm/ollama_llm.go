@@ -1,11 +1,14 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
 type Ollama struct {
@@ -21,6 +24,7 @@ func NewOllama(endpoint string, model string) *Ollama {
 }
 
 // Response represents the structure of the expected response from the API.
+// When streaming, each line of the body is a Response and the last one has Done set.
 type Response struct {
 	Model     string `json:"model"`
 	CreatedAt string `json:"created_at"`
@@ -28,10 +32,12 @@ type Response struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
 	} `json:"message"`
+	Done  bool   `json:"done"`
+	Error string `json:"error"`
 }
 
 // Generate sends a prompt to the Ollama endpoint and returns the response
-func (o *Ollama) Generate(prompt string) (string, error) {
+func (o *Ollama) Generate(ctx context.Context, prompt string) (string, error) {
 	// Create the request payload
 	payload := map[string]interface{}{
 		"model": o.Model,
@@ -51,7 +57,7 @@ func (o *Ollama) Generate(prompt string) (string, error) {
 	}
 
 	// Make the POST request
-	resp, err := http.Post(o.Endpoint, "application/json", bytes.NewBuffer(data))
+	resp, err := o.post(ctx, data)
 	if err != nil {
 		return "", fmt.Errorf("failed to make request: %w", err)
 	}
@@ -77,6 +83,82 @@ func (o *Ollama) Generate(prompt string) (string, error) {
 	return response.Message.Content, nil
 }
 
+// GenerateStream sends a prompt to the Ollama endpoint and reads the NDJSON stream of the response
+func (o *Ollama) GenerateStream(ctx context.Context, prompt string, onDelta func(delta string) error) (string, error) {
+	payload := map[string]interface{}{
+		"model": o.Model,
+		"messages": []map[string]string{
+			{
+				"role":    "user",
+				"content": prompt,
+			},
+		},
+		"stream": true,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	resp, err := o.post(ctx, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API returned error: %s", string(body))
+	}
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var response Response
+		if err := json.Unmarshal(line, &response); err != nil {
+			return full.String(), fmt.Errorf("failed to unmarshal stream line: %w", err)
+		}
+		if response.Error != "" {
+			return full.String(), fmt.Errorf("API returned error: %s", response.Error)
+		}
+
+		if response.Message.Content != "" {
+			full.WriteString(response.Message.Content)
+			if err := onDelta(response.Message.Content); err != nil {
+				return full.String(), err
+			}
+		}
+
+		if response.Done {
+			return full.String(), nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return full.String(), fmt.Errorf("stream ended before completion")
+}
+
+// post sends the payload to the chat endpoint
+func (o *Ollama) post(ctx context.Context, data []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Endpoint, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return http.DefaultClient.Do(req)
+}
+
 func (o *Ollama) GetModel() string {
 	return o.Model
 }
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllamaGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte(`{"model":"llama3.2:3b","message":{"role":"assistant","content":"Hello"},"done":false}
+{"model":"llama3.2:3b","message":{"role":"assistant","content":" world"},"done":false}
+{"model":"llama3.2:3b","message":{"role":"assistant","content":""},"done":true}
+`))
+	}))
+	defer server.Close()
+
+	var deltas []string
+	answer, err := NewOllama(server.URL, "llama3.2:3b").GenerateStream(context.Background(), "Hi", func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	if answer != "Hello world" || len(deltas) != 2 {
+		t.Errorf("GenerateStream() = %q with deltas %q, want %q in 2 deltas", answer, deltas, "Hello world")
+	}
+}
+
+func TestOllamaGenerateStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error":"model not loaded"}` + "\n"))
+	}))
+	defer server.Close()
+
+	_, err := NewOllama(server.URL, "llama3.2:3b").GenerateStream(context.Background(), "Hi", func(string) error { return nil })
+	if err == nil {
+		t.Errorf("GenerateStream() error = nil, want the stream error")
+	}
+}
@@ -1,8 +1,66 @@
 package llm
 
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors returned (wrapped in an *APIError) by the OpenAI-compatible client
+var (
+	ErrUnauthorized  = errors.New("unauthorized")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrContextLength = errors.New("context length exceeded")
+	ErrBadRequest    = errors.New("bad request")
+	ErrNotFound      = errors.New("model or endpoint not found")
+	ErrServer        = errors.New("server error")
+)
+
+// APIError is an error response returned by an OpenAI-compatible API
+type APIError struct {
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("API returned error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("API returned error %d: %s", e.StatusCode, e.Message)
+}
+
+// Unwrap maps the error to one of the sentinel errors, so callers can use errors.Is
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.Code == "context_length_exceeded":
+		return ErrContextLength
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	case e.StatusCode >= http.StatusBadRequest:
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// OpenAI implements LLMService for any OpenAI-compatible /v1/chat/completions API
+// (OpenAI, vLLM, LM Studio, llama.cpp server, ...)
 type OpenAI struct {
 	APIKey   string
-	Endpoint string
+	Endpoint string // Base URL, e.g. https://api.openai.com/v1
 	Model    string
 }
 
@@ -14,11 +72,203 @@ func NewOpenAI(apiKey string, endpoint string, model string) *OpenAI {
 	}
 }
 
-func (o *OpenAI) Generate(prompt string) (string, error) {
-	return "", nil
-	// TODO: implement
+// chatMessage is a single message of a chat completion
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the payload of a chat completion request
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+// chatResponse represents the structure of a chat completion response
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Index        int         `json:"index"`
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// chatStreamChunk is a single server-sent event of a streamed chat completion
+type chatStreamChunk struct {
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// errorResponse is the error body returned by OpenAI-compatible APIs
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"` // string for OpenAI, number for some compatible servers
+	} `json:"error"`
+}
+
+// Generate sends the prompt as a user message and returns the content of the first choice
+func (o *OpenAI) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := o.post(ctx, prompt, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", parseAPIError(resp.StatusCode, body)
+	}
+
+	var response chatResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return "", fmt.Errorf("API returned no choices")
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// GenerateStream sends the prompt with "stream": true and reads the server-sent events of the response
+func (o *OpenAI) GenerateStream(ctx context.Context, prompt string, onDelta func(delta string) error) (string, error) {
+	resp, err := o.post(ctx, prompt, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", parseAPIError(resp.StatusCode, body)
+	}
+
+	var full strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// skip blank separators, comments and event names
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			return full.String(), nil
+		}
+
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return full.String(), fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+		if chunk.Error != nil {
+			return full.String(), &APIError{StatusCode: resp.StatusCode, Type: chunk.Error.Type, Message: chunk.Error.Message}
+		}
+
+		for _, choice := range chunk.Choices {
+			if choice.Index != 0 || choice.Delta.Content == "" {
+				continue
+			}
+			full.WriteString(choice.Delta.Content)
+			if err := onDelta(choice.Delta.Content); err != nil {
+				return full.String(), err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return full.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	// some compatible servers close the stream without [DONE]
+	return full.String(), nil
 }
 
 func (o *OpenAI) GetModel() string {
 	return o.Model
 }
+
+// post sends a chat completion request for the prompt
+func (o *OpenAI) post(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	payload := chatRequest{
+		Model: o.Model,
+		Messages: []chatMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		Stream: stream,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url(), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// url returns the chat completions URL, accepting either a base URL or the full path
+func (o *OpenAI) url() string {
+	endpoint := strings.TrimRight(o.Endpoint, "/")
+	if strings.HasSuffix(endpoint, "/chat/completions") {
+		return endpoint
+	}
+	return endpoint + "/chat/completions"
+}
+
+// parseAPIError builds an *APIError from an error response, falling back to the raw body
+func parseAPIError(statusCode int, body []byte) error {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+
+	var response errorResponse
+	if err := json.Unmarshal(body, &response); err == nil && response.Error.Message != "" {
+		apiErr.Message = response.Error.Message
+		apiErr.Type = response.Error.Type
+		if response.Error.Code != nil {
+			apiErr.Code = fmt.Sprint(response.Error.Code)
+		}
+	}
+
+	return apiErr
+}
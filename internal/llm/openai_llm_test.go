@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s, want /v1/chat/completions", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+		}
+
+		var request chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if request.Model != "gpt-4o-mini" || len(request.Messages) != 1 || request.Messages[0].Content != "Hello" {
+			t.Errorf("unexpected request %+v", request)
+		}
+
+		w.Write([]byte(`{"model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"Hi there"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	o := NewOpenAI("secret", server.URL+"/v1", "gpt-4o-mini")
+	answer, err := o.Generate(context.Background(), "Hello")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if answer != "Hi there" {
+		t.Errorf("Generate() = %q, want %q", answer, "Hi there")
+	}
+}
+
+func TestOpenAIGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`, ErrUnauthorized},
+		{"rate limited", http.StatusTooManyRequests, `{"error":{"message":"Rate limit reached","type":"requests"}}`, ErrRateLimited},
+		{"context length", http.StatusBadRequest, `{"error":{"message":"too long","code":"context_length_exceeded"}}`, ErrContextLength},
+		{"server error", http.StatusBadGateway, `upstream unavailable`, ErrServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewOpenAI("", server.URL, "model").Generate(context.Background(), "Hello")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Generate() error = %v, want %v", err, tt.want)
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Errorf("Generate() error = %v, want APIError with status %d", err, tt.status)
+			}
+		})
+	}
+}
+
+func TestOpenAIGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request chatRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		if !request.Stream {
+			t.Errorf("stream = false, want true")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+			": keep-alive\n\n" +
+			"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n" +
+			"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\" there\"},\"finish_reason\":\"stop\"}]}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	var deltas []string
+	answer, err := NewOpenAI("", server.URL, "model").GenerateStream(context.Background(), "Hello", func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream() error = %v", err)
+	}
+	if answer != "Hi there" || len(deltas) != 2 {
+		t.Errorf("GenerateStream() = %q with deltas %q, want %q in 2 deltas", answer, deltas, "Hi there")
+	}
+}
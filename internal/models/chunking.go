@@ -0,0 +1,9 @@
+package models
+
+// Chunking selects how the content of a document is split into chunks
+type Chunking struct {
+	Strategy   string  `json:"strategy,omitempty"`   // sentence | fixed | recursive | markdown | token | semantic
+	Size       int     `json:"size,omitempty"`       // Maximum chunk size, in characters or in tokens for "token", 0 is the strategy default
+	Overlap    int     `json:"overlap,omitempty"`    // Size repeated from the end of the previous chunk
+	Percentile float64 `json:"percentile,omitempty"` // Similarity percentile below which "semantic" cuts, 0 is the default
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Filter restricts a search to the chunks of the matching documents.
+// Every set condition must match; an empty filter matches the latest version of every document.
+type Filter struct {
+	Categories []string          `json:"categories,omitempty"`  // Document category is one of these
+	Metadata   map[string]string `json:"metadata,omitempty"`    // Every key has exactly this value in the document metadata
+	Filename   string            `json:"filename,omitempty"`    // Document filename equals this
+	LinkPrefix string            `json:"link_prefix,omitempty"` // Document link starts with this
+	AsOf       *time.Time        `json:"as_of,omitempty"`       // Chunks of the versions current at this time instead of the latest versions
+}
+
+// IsEmpty reports whether the filter has no condition
+func (f Filter) IsEmpty() bool {
+	return len(f.Categories) == 0 && len(f.Metadata) == 0 && f.Filename == "" && f.LinkPrefix == "" && f.AsOf == nil
+}
+
+// Match reports whether the chunk belongs to a document matching the filter
+func (f Filter) Match(e Embedding) bool {
+	if len(f.Categories) > 0 && !contains(f.Categories, e.Category) {
+		return false
+	}
+	for key, value := range f.Metadata {
+		if v, ok := e.Metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	if f.Filename != "" && e.Filename != f.Filename {
+		return false
+	}
+	if f.LinkPrefix != "" && !strings.HasPrefix(e.Link, f.LinkPrefix) {
+		return false
+	}
+	return e.ValidAt(f.AsOf)
+}
+
+// ValidAt reports whether the chunk is part of its document at the given time, or in the
+// latest version of the document when at is nil
+func (e Embedding) ValidAt(at *time.Time) bool {
+	if at == nil {
+		return e.ValidTo == 0
+	}
+	t := at.Unix()
+	return e.ValidFrom <= t && (e.ValidTo == 0 || e.ValidTo > t)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
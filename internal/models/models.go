@@ -13,6 +13,14 @@ type Document struct {
 	Summary        string            `json:"summary" milvus:"Summary"`                // Summary of the document
 	Metadata       map[string]string `json:"metadata" milvus:"Metadata"`              // Additional metadata (e.g., author, timestamp)
 	Vector         []float32         `json:"vector" milvus:"Vector"`
+	ExternalID     string            `json:"external_id,omitempty" milvus:"ExternalID"` // Identifier given by the client, matches re-uploads of the document
+	ContentHash    string            `json:"content_hash" milvus:"ContentHash"`         // SHA-256 of the content, matches re-uploads of the same content
+	Version        int64             `json:"version" milvus:"Version"`                  // Incremented by each content change, starts at 1
+	UpdatedAt      int64             `json:"updated_at" milvus:"UpdatedAt"`             // Unix time the current version was created
+	Sections       []Section         `json:"sections,omitempty"`                        // Extracted parts of Content with their page/heading, not stored. Queued without Content, rebuilt from them
+	Chunking       *Chunking         `json:"chunking,omitempty"`                        // How Content is chunked, the default if nil, recorded in Metadata once ingested
+	Mode           string            `json:"mode,omitempty"`                            // What to do when the document was already uploaded: skip | replace | new_version, not stored
+	Update         *FieldUpdate      `json:"update,omitempty"`                          // Fields changed by a queued update, applied to the document as it is when processed, not stored
 }
 
 // Embedding represents the vector embedding for a document or query
@@ -23,5 +31,14 @@ type Embedding struct {
 	TextChunk  string    `json:"text_chunk" milvus:"TextChunk"`   // Text chunk of the document
 	Dimension  int64     `json:"dimension" milvus:"Dimension"`    // Dimensionality of the vector
 	Order      int64     `json:"order" milvus:"Order"`            // Order of the embedding to build the content back
-	Score      float32   `json:"score"`                           // Score of the embedding
+	Score      float32   `json:"score"`                           // Similarity to the query, higher is better, 0 when only the keyword search found the chunk
+	FusedScore float32   `json:"fused_score,omitempty"`           // Reciprocal rank fusion score of hybrid retrieval, which ranks the chunks by it
+	ValidFrom  int64     `json:"valid_from" milvus:"ValidFrom"`   // Unix time the chunk became part of the document
+	ValidTo    int64     `json:"valid_to" milvus:"ValidTo"`       // Unix time a new version replaced the chunk, 0 while current
+
+	// Copied from the document so searches can filter on them
+	Filename string            `json:"filename,omitempty" milvus:"Filename"`
+	Link     string            `json:"link,omitempty" milvus:"Link"`
+	Category string            `json:"category,omitempty" milvus:"Category"`
+	Metadata map[string]string `json:"metadata,omitempty" milvus:"Metadata"`
 }
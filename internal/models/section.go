@@ -0,0 +1,9 @@
+package models
+
+// Section is a part of a document found by text extraction, e.g. a PDF page or the text
+// under a heading. Its position is kept as metadata of the chunks created from it.
+type Section struct {
+	Text    string `json:"text"`
+	Page    int    `json:"page,omitempty"`    // 1-based page number, 0 if the format has no pages
+	Heading string `json:"heading,omitempty"` // Heading path, e.g. "Installation > Linux"
+}
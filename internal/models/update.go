@@ -0,0 +1,11 @@
+package models
+
+// FieldUpdate changes the link, filename, category and metadata of a document, nil fields
+// keep their value
+type FieldUpdate struct {
+	Link            *string            `json:"link,omitempty"`
+	Filename        *string            `json:"filename,omitempty"`
+	Category        *string            `json:"category,omitempty"`
+	Metadata        map[string]*string `json:"metadata,omitempty"`         // Keys set to their value, removed when nil
+	ReplaceMetadata bool               `json:"replace_metadata,omitempty"` // Metadata replaces the whole metadata instead of being merged
+}
@@ -0,0 +1,332 @@
+package bm25
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BM25 parameters: k1 saturates the term frequency, b normalizes by chunk length
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// chunksBucket is the bbolt bucket of the indexed chunks, keyed by chunk ID
+var chunksBucket = []byte("chunks")
+
+// entry is an indexed chunk: only what scoring and validity need, the chunk itself stays
+// in the vector store
+type entry struct {
+	DocumentID string         `json:"document_id"`
+	ValidFrom  int64          `json:"valid_from,omitempty"`
+	ValidTo    int64          `json:"valid_to,omitempty"`
+	Terms      map[string]int `json:"terms"` // term -> frequency in the chunk
+	length     int            // Number of terms of the chunk
+}
+
+// Hit is a chunk matching a keyword search
+type Hit struct {
+	ChunkID string
+	Score   float64 // BM25 score
+}
+
+// Index is an in-process inverted index over the chunk texts, scored with BM25.
+// The terms of each chunk are persisted in a bbolt file, written chunk by chunk as the
+// index changes, and the postings are rebuilt from them on open.
+type Index struct {
+	mu          sync.RWMutex
+	db          *bolt.DB                   // nil keeps the index in memory only
+	entries     map[string]*entry          // chunk ID -> entry
+	postings    map[string]map[string]int  // term -> chunk ID -> term frequency
+	documents   map[string]map[string]bool // document ID -> chunk IDs
+	totalLength int
+}
+
+// NewIndex opens the index persisted in path, loading the chunks already stored there.
+// An empty path keeps the index in memory only.
+func NewIndex(path string) (*Index, error) {
+	idx := &Index{
+		entries:   make(map[string]*entry),
+		postings:  make(map[string]map[string]int),
+		documents: make(map[string]map[string]bool),
+	}
+
+	if path == "" {
+		return idx, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create keyword index directory: %w", err)
+	}
+
+	// another process holding the file makes Open fail after the timeout instead of blocking
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open keyword index %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(chunksBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var e entry
+			if err := json.Unmarshal(value, &e); err != nil {
+				return fmt.Errorf("failed to unmarshal chunk %s: %w", key, err)
+			}
+			idx.add(string(key), &e)
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load keyword index %s: %w", path, err)
+	}
+
+	idx.db = db
+	return idx, nil
+}
+
+// Close closes the index file
+func (idx *Index) Close() error {
+	if idx.db == nil {
+		return nil
+	}
+	return idx.db.Close()
+}
+
+// Add indexes the chunks, replacing the ones already indexed with the same ID
+func (idx *Index) Add(chunks []models.Embedding) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	added := make(map[string]*entry, len(chunks))
+	for _, chunk := range chunks {
+		terms := make(map[string]int)
+		for _, term := range Tokenize(chunk.TextChunk) {
+			terms[term]++
+		}
+		added[chunk.ID] = &entry{DocumentID: chunk.DocumentID, ValidFrom: chunk.ValidFrom, ValidTo: chunk.ValidTo, Terms: terms}
+	}
+
+	if err := idx.persist(added, nil); err != nil {
+		return err
+	}
+	for chunkID, e := range added {
+		idx.remove(chunkID)
+		idx.add(chunkID, e)
+	}
+	return nil
+}
+
+// Remove removes the chunks with the given IDs
+func (idx *Index) Remove(chunkIDs []string) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.persist(nil, chunkIDs); err != nil {
+		return err
+	}
+	for _, chunkID := range chunkIDs {
+		idx.remove(chunkID)
+	}
+	return nil
+}
+
+// RemoveDocument removes every chunk of the document
+func (idx *Index) RemoveDocument(documentID string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	var chunkIDs []string
+	for chunkID := range idx.documents[documentID] {
+		chunkIDs = append(chunkIDs, chunkID)
+	}
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+
+	if err := idx.persist(nil, chunkIDs); err != nil {
+		return err
+	}
+	for _, chunkID := range chunkIDs {
+		idx.remove(chunkID)
+	}
+	return nil
+}
+
+// Reset removes every indexed chunk
+func (idx *Index) Reset() error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.db != nil {
+		err := idx.db.Update(func(tx *bolt.Tx) error {
+			if err := tx.DeleteBucket(chunksBucket); err != nil {
+				return err
+			}
+			_, err := tx.CreateBucket(chunksBucket)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reset keyword index: %w", err)
+		}
+	}
+
+	idx.entries = make(map[string]*entry)
+	idx.postings = make(map[string]map[string]int)
+	idx.documents = make(map[string]map[string]bool)
+	idx.totalLength = 0
+	return nil
+}
+
+// Len returns the number of indexed chunks
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
+
+// Search returns up to limit chunks with the best BM25 score for the query among the chunks
+// part of their document at asOf, or of the latest version when asOf is nil, best first
+func (idx *Index) Search(query string, limit int, asOf *time.Time) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.entries) == 0 {
+		return nil
+	}
+
+	n := float64(len(idx.entries))
+	avgLength := float64(idx.totalLength) / n
+
+	scores := make(map[string]float64)
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := idx.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for chunkID, tf := range postings {
+			length := float64(idx.entries[chunkID].length)
+			freq := float64(tf)
+			scores[chunkID] += idf * freq * (k1 + 1) / (freq + k1*(1-b+b*length/avgLength))
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for chunkID, score := range scores {
+		e := idx.entries[chunkID]
+		if !(models.Embedding{ValidFrom: e.ValidFrom, ValidTo: e.ValidTo}).ValidAt(asOf) {
+			continue
+		}
+		hits = append(hits, Hit{ChunkID: chunkID, Score: score})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ChunkID < hits[j].ChunkID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
+
+func (idx *Index) add(chunkID string, e *entry) {
+	for _, tf := range e.Terms {
+		e.length += tf
+	}
+	idx.entries[chunkID] = e
+	idx.totalLength += e.length
+
+	for term, tf := range e.Terms {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]int)
+		}
+		idx.postings[term][chunkID] = tf
+	}
+
+	if idx.documents[e.DocumentID] == nil {
+		idx.documents[e.DocumentID] = make(map[string]bool)
+	}
+	idx.documents[e.DocumentID][chunkID] = true
+}
+
+func (idx *Index) remove(chunkID string) {
+	e, ok := idx.entries[chunkID]
+	if !ok {
+		return
+	}
+
+	for term := range e.Terms {
+		delete(idx.postings[term], chunkID)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+
+	delete(idx.documents[e.DocumentID], chunkID)
+	if len(idx.documents[e.DocumentID]) == 0 {
+		delete(idx.documents, e.DocumentID)
+	}
+
+	idx.totalLength -= e.length
+	delete(idx.entries, chunkID)
+}
+
+// persist writes the added chunks and deletes the removed ones in a single transaction,
+// the rest of the file is left as it is
+func (idx *Index) persist(added map[string]*entry, removed []string) error {
+	if idx.db == nil {
+		return nil
+	}
+
+	err := idx.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(chunksBucket)
+		for chunkID, e := range added {
+			value, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(chunkID), value); err != nil {
+				return err
+			}
+		}
+		for _, chunkID := range removed {
+			if err := bucket.Delete([]byte(chunkID)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write keyword index: %w", err)
+	}
+	return nil
+}
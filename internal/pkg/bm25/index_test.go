@@ -0,0 +1,143 @@
+package bm25
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("See ISO 27001, clause A.12.4 (logging).")
+	want := []string{"see", "iso", "27001", "clause", "a.12.4", "a", "12", "4", "logging"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyword.db")
+	idx, err := NewIndex(path)
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+
+	chunks := []models.Embedding{
+		{ID: "c1", DocumentID: "d1", TextChunk: "Control A.12.4 requires event logging.", Category: "iso"},
+		{ID: "c2", DocumentID: "d1", TextChunk: "Control A.12.1 covers operational procedures.", Category: "iso"},
+		{ID: "c3", DocumentID: "d2", TextChunk: "Event logging in the application.", Category: "dev"},
+	}
+	if err := idx.Add(chunks); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	hits := idx.Search("A.12.4", 10, nil)
+	if len(hits) == 0 || hits[0].ChunkID != "c1" {
+		t.Fatalf("Search() = %v, want c1 first", hits)
+	}
+
+	hits = idx.Search("event logging", 1, nil)
+	if len(hits) != 1 {
+		t.Errorf("Search() with limit 1 = %v, want one hit", hits)
+	}
+
+	// c2 is removed and c3 re-indexed with another text, each change is written on its own
+	if err := idx.Remove([]string{"c2"}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := idx.Add([]models.Embedding{{ID: "c3", DocumentID: "d2", TextChunk: "Audit trail of the application."}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// the index survives a restart
+	reloaded, err := NewIndex(path)
+	if err != nil {
+		t.Fatalf("NewIndex() reload error = %v", err)
+	}
+	defer reloaded.Close()
+	if reloaded.Len() != 2 {
+		t.Errorf("reloaded Len() = %d, want 2", reloaded.Len())
+	}
+	if hits := reloaded.Search("audit logging", 10, nil); len(hits) != 2 || hits[0].ChunkID != "c3" {
+		t.Errorf("reloaded Search() = %v, want c3 then c1", hits)
+	}
+
+	if err := reloaded.RemoveDocument("d1"); err != nil {
+		t.Fatalf("RemoveDocument() error = %v", err)
+	}
+	if hits := reloaded.Search("A.12.4", 10, nil); len(hits) != 0 {
+		t.Errorf("Search() after RemoveDocument = %v, want none", hits)
+	}
+}
+
+func TestIndexSearchVersions(t *testing.T) {
+	idx, err := NewIndex("")
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+
+	// c1 was replaced by c2 at 2000
+	chunks := []models.Embedding{
+		{ID: "c1", DocumentID: "d1", TextChunk: "Passwords expire every 90 days.", ValidFrom: 1000, ValidTo: 2000},
+		{ID: "c2", DocumentID: "d1", TextChunk: "Passwords expire every 180 days.", ValidFrom: 2000},
+	}
+	if err := idx.Add(chunks); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	hits := idx.Search("passwords expire", 10, nil)
+	if len(hits) != 1 || hits[0].ChunkID != "c2" {
+		t.Errorf("Search() = %v, want only the latest c2", hits)
+	}
+
+	asOf := time.Unix(1500, 0)
+	hits = idx.Search("passwords expire", 10, &asOf)
+	if len(hits) != 1 || hits[0].ChunkID != "c1" {
+		t.Errorf("Search() as of 1500 = %v, want only c1", hits)
+	}
+
+	if err := idx.Remove([]string{"c1"}); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if idx.Len() != 1 {
+		t.Errorf("Len() after Remove = %d, want 1", idx.Len())
+	}
+}
+
+func TestIndexReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyword.db")
+	idx, err := NewIndex(path)
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+	if err := idx.Add([]models.Embedding{{ID: "c1", DocumentID: "d1", TextChunk: "Event logging."}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := idx.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if idx.Len() != 0 || len(idx.Search("logging", 10, nil)) != 0 {
+		t.Errorf("Reset() left %d chunks", idx.Len())
+	}
+
+	// the chunks indexed after a reset are persisted
+	if err := idx.Add([]models.Embedding{{ID: "c2", DocumentID: "d2", TextChunk: "Access control."}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := idx.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	reloaded, err := NewIndex(path)
+	if err != nil {
+		t.Fatalf("NewIndex() reload error = %v", err)
+	}
+	defer reloaded.Close()
+	if hits := reloaded.Search("logging access", 10, nil); len(hits) != 1 || hits[0].ChunkID != "c2" {
+		t.Errorf("reloaded Search() = %v, want c2 only", hits)
+	}
+}
@@ -0,0 +1,32 @@
+package bm25
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize lowercases the text and splits it into terms. Identifiers joined by dots or
+// dashes ("A.12.4", "ISO-27001") are kept whole and also split into their parts, so
+// both the exact identifier and its pieces can match.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '-'
+	})
+
+	var terms []string
+	for _, word := range words {
+		word = strings.Trim(word, ".-")
+		if word == "" {
+			continue
+		}
+		terms = append(terms, word)
+
+		if !strings.ContainsAny(word, ".-") {
+			continue
+		}
+		for _, part := range strings.FieldsFunc(word, func(r rune) bool { return r == '.' || r == '-' }) {
+			terms = append(terms, part)
+		}
+	}
+	return terms
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
@@ -11,12 +12,22 @@ import (
 )
 
 type Client struct {
-	Instance client.Client
+	Instance        client.Client
+	Dim             int               // Dimension of the vectors stored in the collections
+	DocumentsMetric entity.MetricType // Metric of the "documents" vector index
+	ChunksMetric    entity.MetricType // Metric of the "chunks" vector index
 }
 
 // InitMilvusClient initializes the Milvus client and returns a wrapper around it.
-func NewClient(milvusAddr string) (*Client, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// dim is the dimension of the embedding model, the collections are created with it
+// and an existing collection with another dimension is rejected. The vector indexes are
+// created with the given metrics, an existing index with another metric is rejected.
+func NewClient(ctx context.Context, milvusAddr string, dim int, documentsMetric, chunksMetric entity.MetricType) (*Client, error) {
+	if dim <= 0 {
+		return nil, fmt.Errorf("invalid vector dimension %d", dim)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	c, err := client.NewClient(ctx, client.Config{Address: milvusAddr})
@@ -25,7 +36,7 @@ func NewClient(milvusAddr string) (*Client, error) {
 		return nil, err
 	}
 
-	client := &Client{Instance: c}
+	client := &Client{Instance: c, Dim: dim, DocumentsMetric: documentsMetric, ChunksMetric: chunksMetric}
 
 	err = client.EnsureCollections(ctx)
 	if err != nil {
@@ -48,18 +59,18 @@ func (m *Client) EnsureCollections(ctx context.Context) error {
 	}{
 		{
 			Name:       "documents",
-			Schema:     createDocumentSchema(),
+			Schema:     createDocumentSchema(m.Dim),
 			IndexField: "Vector", // Indexing the Vector field for similarity search
 			IndexType:  "IVF_FLAT",
-			MetricType: entity.L2,
+			MetricType: m.DocumentsMetric,
 			Nlist:      10, // Number of clusters for IVF_FLAT index
 		},
 		{
 			Name:       "chunks",
-			Schema:     createEmbeddingSchema(),
+			Schema:     createEmbeddingSchema(m.Dim),
 			IndexField: "Vector", // Indexing the Vector field for similarity search
 			IndexType:  "IVF_FLAT",
-			MetricType: entity.L2,
+			MetricType: m.ChunksMetric,
 			Nlist:      10,
 		},
 	}
@@ -77,14 +88,48 @@ func (m *Client) EnsureCollections(ctx context.Context) error {
 			return fmt.Errorf("failed to check collection existence: %w", err)
 		}
 
-		if !exists {
+		// a collection left by an interrupted migration is migrated again
+		source := collection.Name + migrationSuffix
+		migrating, err := m.Instance.HasCollection(ctx, source)
+		if err != nil {
+			return fmt.Errorf("failed to check collection existence: %w", err)
+		}
+
+		if exists && !migrating {
+			log.Printf("Collection '%s' already exists", collection.Name)
+
+			missing, err := m.checkSchema(ctx, collection.Schema)
+			if err != nil {
+				return err
+			}
+
+			if len(missing) > 0 {
+				log.Printf("Collection '%s' has no %v fields, migrating it", collection.Name, missing)
+				if err := m.Instance.RenameCollection(ctx, collection.Name, source); err != nil {
+					return fmt.Errorf("failed to rename collection '%s': %w", collection.Name, err)
+				}
+				exists, migrating = false, true
+			} else if err := m.checkMetric(ctx, collection.Name, collection.IndexField, collection.MetricType); err != nil {
+				return err
+			}
+		}
+
+		if migrating {
+			// the partial copy of an interrupted migration is started again
+			if exists {
+				if err := m.Instance.DropCollection(ctx, collection.Name); err != nil {
+					return fmt.Errorf("failed to drop collection '%s': %w", collection.Name, err)
+				}
+			}
+			if err := m.migrateCollection(ctx, collection.Schema, source); err != nil {
+				return err
+			}
+		} else if !exists {
 			err := m.Instance.CreateCollection(ctx, collection.Schema, entity.DefaultShardNumber)
 			if err != nil {
 				return fmt.Errorf("failed to create collection '%s': %w", collection.Name, err)
 			}
 			log.Printf("Collection '%s' created successfully", collection.Name)
-		} else {
-			log.Printf("Collection '%s' already exists", collection.Name)
 		}
 
 		// Ensure the default partition exists
@@ -135,8 +180,66 @@ func (m *Client) EnsureCollections(ctx context.Context) error {
 	return nil
 }
 
+// checkSchema returns the fields of the expected schema an existing collection misses, e.g.
+// one created by an older version, and fails when its vector field doesn't match the model dimension
+func (m *Client) checkSchema(ctx context.Context, schema *entity.Schema) ([]string, error) {
+	collectionName := schema.CollectionName
+	coll, err := m.Instance.DescribeCollection(ctx, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe collection '%s': %w", collectionName, err)
+	}
+
+	existing := make(map[string]*entity.Field, len(coll.Schema.Fields))
+	for _, field := range coll.Schema.Fields {
+		existing[field.Name] = field
+	}
+	var missing []string
+	for _, field := range schema.Fields {
+		if _, ok := existing[field.Name]; !ok {
+			missing = append(missing, field.Name)
+		}
+	}
+
+	for _, field := range coll.Schema.Fields {
+		if field.Name != "Vector" {
+			continue
+		}
+
+		dim, err := strconv.Atoi(field.TypeParams[entity.TypeParamDim])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vector dimension of collection '%s': %w", collectionName, err)
+		}
+		if dim != m.Dim {
+			return nil, fmt.Errorf("collection '%s' stores vectors of dimension %d but the embedding model produces %d: "+
+				"drop the collection or configure a matching model", collectionName, dim, m.Dim)
+		}
+		return missing, nil
+	}
+
+	return nil, fmt.Errorf("collection '%s' has no Vector field", collectionName)
+}
+
+// checkMetric fails when the existing vector index of a collection uses another metric
+func (m *Client) checkMetric(ctx context.Context, collectionName string, field string, metric entity.MetricType) error {
+	indexes, err := m.Instance.DescribeIndex(ctx, collectionName, field)
+	if err != nil {
+		// no index yet, it is created with the configured metric
+		return nil
+	}
+
+	for _, index := range indexes {
+		existing := entity.MetricType(index.Params()["metric_type"])
+		if existing != "" && existing != metric {
+			return fmt.Errorf("collection '%s' is indexed with metric %s but %s is configured: "+
+				"configure %s or drop the collection", collectionName, existing, metric, existing)
+		}
+	}
+
+	return nil
+}
+
 // Helper functions for creating schemas
-func createDocumentSchema() *entity.Schema {
+func createDocumentSchema(dim int) *entity.Schema {
 	return entity.NewSchema().
 		WithName("documents").
 		WithDescription("Collection for storing documents").
@@ -148,19 +251,33 @@ func createDocumentSchema() *entity.Schema {
 		WithField(entity.NewField().WithName("EmbeddingModel").WithDataType(entity.FieldTypeVarChar).WithMaxLength(256)).
 		WithField(entity.NewField().WithName("Summary").WithDataType(entity.FieldTypeVarChar).WithMaxLength(65535)).
 		WithField(entity.NewField().WithName("Metadata").WithDataType(entity.FieldTypeVarChar).WithMaxLength(65535)).
-		WithField(entity.NewField().WithName("Vector").WithDataType(entity.FieldTypeFloatVector).WithDim(1024)) // bge-m3
+		WithField(entity.NewField().WithName("ExternalID").WithDataType(entity.FieldTypeVarChar).WithMaxLength(512)).
+		WithField(entity.NewField().WithName("ContentHash").WithDataType(entity.FieldTypeVarChar).WithMaxLength(64)).
+		WithField(entity.NewField().WithName("Version").WithDataType(entity.FieldTypeInt64)).
+		WithField(entity.NewField().WithName("UpdatedAt").WithDataType(entity.FieldTypeInt64)).
+		WithField(entity.NewField().WithName("Vector").WithDataType(entity.FieldTypeFloatVector).WithDim(int64(dim)))
 }
 
-func createEmbeddingSchema() *entity.Schema {
+// TextChunkMaxLength is the VarChar max length of the TextChunk field, in bytes
+const TextChunkMaxLength = 65535
+
+func createEmbeddingSchema(dim int) *entity.Schema {
 	return entity.NewSchema().
 		WithName("chunks").
 		WithDescription("Collection for storing document embeddings").
 		WithField(entity.NewField().WithName("ID").WithDataType(entity.FieldTypeVarChar).WithIsPrimaryKey(true).WithMaxLength(512)).
 		WithField(entity.NewField().WithName("DocumentID").WithDataType(entity.FieldTypeVarChar).WithMaxLength(512)).
-		WithField(entity.NewField().WithName("Vector").WithDataType(entity.FieldTypeFloatVector).WithDim(1024)). // bge-m3
-		WithField(entity.NewField().WithName("TextChunk").WithDataType(entity.FieldTypeVarChar).WithMaxLength(65535)).
+		WithField(entity.NewField().WithName("Vector").WithDataType(entity.FieldTypeFloatVector).WithDim(int64(dim))).
+		WithField(entity.NewField().WithName("TextChunk").WithDataType(entity.FieldTypeVarChar).WithMaxLength(TextChunkMaxLength)).
 		WithField(entity.NewField().WithName("Dimension").WithDataType(entity.FieldTypeInt32)).
-		WithField(entity.NewField().WithName("Order").WithDataType(entity.FieldTypeInt32))
+		WithField(entity.NewField().WithName("Order").WithDataType(entity.FieldTypeInt32)).
+		WithField(entity.NewField().WithName("ValidFrom").WithDataType(entity.FieldTypeInt64)).
+		WithField(entity.NewField().WithName("ValidTo").WithDataType(entity.FieldTypeInt64)).
+		// document fields copied on each chunk, used by search filters
+		WithField(entity.NewField().WithName("Filename").WithDataType(entity.FieldTypeVarChar).WithMaxLength(512)).
+		WithField(entity.NewField().WithName("Link").WithDataType(entity.FieldTypeVarChar).WithMaxLength(512)).
+		WithField(entity.NewField().WithName("Category").WithDataType(entity.FieldTypeVarChar).WithMaxLength(8048)).
+		WithField(entity.NewField().WithName("Metadata").WithDataType(entity.FieldTypeJSON))
 }
 
 // Close closes the Milvus client connection.
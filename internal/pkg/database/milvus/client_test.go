@@ -1,13 +1,17 @@
 package milvus
 
 import (
+	"context"
 	"reflect"
 	"testing"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 )
 
 func TestNewClient(t *testing.T) {
 	type args struct {
 		milvusAddr string
+		dim        int
 	}
 	tests := []struct {
 		name    string
@@ -19,7 +23,7 @@ func TestNewClient(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewClient(tt.args.milvusAddr)
+			got, err := NewClient(context.Background(), tt.args.milvusAddr, tt.args.dim, entity.L2, entity.L2)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
 				return
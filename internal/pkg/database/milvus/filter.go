@@ -0,0 +1,66 @@
+package milvus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// filterExpression converts a filter into a Milvus boolean expression on the "chunks" collection.
+// An empty filter matches the chunks of the latest versions.
+func filterExpression(filter models.Filter) string {
+	var conditions []string
+
+	if len(filter.Categories) > 0 {
+		categories := make([]string, len(filter.Categories))
+		for i, category := range filter.Categories {
+			categories[i] = quote(category)
+		}
+		conditions = append(conditions, fmt.Sprintf("Category in [%s]", strings.Join(categories, ", ")))
+	}
+
+	// sorted keys keep the expression stable
+	keys := make([]string, 0, len(filter.Metadata))
+	for key := range filter.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conditions = append(conditions, fmt.Sprintf("Metadata[%s] == %s", quote(key), quote(filter.Metadata[key])))
+	}
+
+	if filter.Filename != "" {
+		conditions = append(conditions, fmt.Sprintf("Filename == %s", quote(filter.Filename)))
+	}
+
+	if filter.LinkPrefix != "" {
+		conditions = append(conditions, fmt.Sprintf("Link like %s", quote(escapeLike(filter.LinkPrefix)+"%")))
+	}
+
+	conditions = append(conditions, validityExpression(filter.AsOf))
+
+	return strings.Join(conditions, " && ")
+}
+
+// validityExpression matches the chunks part of their document at the given time, or in the
+// latest version of the document when at is nil
+func validityExpression(at *time.Time) string {
+	if at == nil {
+		return "ValidTo == 0"
+	}
+	t := at.Unix()
+	return fmt.Sprintf("ValidFrom <= %d && (ValidTo == 0 || ValidTo > %d)", t, t)
+}
+
+// quote returns s as a Milvus string literal
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// escapeLike escapes the wildcards of a like pattern so s is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,45 @@
+package milvus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestFilterExpression(t *testing.T) {
+	asOf := time.Unix(1714564800, 0)
+	tests := []struct {
+		name   string
+		filter models.Filter
+		want   string
+	}{
+		{"empty", models.Filter{}, `ValidTo == 0`},
+		{"categories", models.Filter{Categories: []string{"news", "blog"}}, `Category in ["news", "blog"] && ValidTo == 0`},
+		{
+			"metadata",
+			models.Filter{Metadata: map[string]string{"tenant": "acme", "author": "bob"}},
+			`Metadata["author"] == "bob" && Metadata["tenant"] == "acme" && ValidTo == 0`,
+		},
+		{"filename", models.Filter{Filename: "report.pdf"}, `Filename == "report.pdf" && ValidTo == 0`},
+		{"link prefix", models.Filter{LinkPrefix: "https://example.com/docs_"}, `Link like "https://example.com/docs\\_%" && ValidTo == 0`},
+		{"quotes", models.Filter{Filename: `a "quoted" name`}, `Filename == "a \"quoted\" name" && ValidTo == 0`},
+		{
+			"combined",
+			models.Filter{Categories: []string{"news"}, Metadata: map[string]string{"tenant": "acme"}},
+			`Category in ["news"] && Metadata["tenant"] == "acme" && ValidTo == 0`,
+		},
+		{
+			"as of",
+			models.Filter{AsOf: &asOf},
+			`ValidFrom <= 1714564800 && (ValidTo == 0 || ValidTo > 1714564800)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterExpression(tt.filter); got != tt.want {
+				t.Errorf("filterExpression() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,42 @@ func extractIDs(docs []models.Document) []string {
 	return ids
 }
 
+// extractExternalIDs extracts the "ExternalID" field from the documents.
+func extractExternalIDs(docs []models.Document) []string {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ExternalID
+	}
+	return ids
+}
+
+// extractContentHashes extracts the "ContentHash" field from the documents.
+func extractContentHashes(docs []models.Document) []string {
+	hashes := make([]string, len(docs))
+	for i, doc := range docs {
+		hashes[i] = doc.ContentHash
+	}
+	return hashes
+}
+
+// extractVersions extracts the "Version" field from the documents.
+func extractVersions(docs []models.Document) []int64 {
+	versions := make([]int64, len(docs))
+	for i, doc := range docs {
+		versions[i] = doc.Version
+	}
+	return versions
+}
+
+// extractUpdatedAts extracts the "UpdatedAt" field from the documents.
+func extractUpdatedAts(docs []models.Document) []int64 {
+	times := make([]int64, len(docs))
+	for i, doc := range docs {
+		times[i] = doc.UpdatedAt
+	}
+	return times
+}
+
 // extractLinks extracts the "Link" field from the documents.
 func extractLinks(docs []models.Document) []string {
 	links := make([]string, len(docs))
@@ -150,6 +186,64 @@ func extractOrders(embeddings []models.Embedding) []int32 {
 	return orders
 }
 
+// extractValidFroms extracts the "ValidFrom" field from the embeddings.
+func extractValidFroms(embeddings []models.Embedding) []int64 {
+	times := make([]int64, len(embeddings))
+	for i, embedding := range embeddings {
+		times[i] = embedding.ValidFrom
+	}
+	return times
+}
+
+// extractValidTos extracts the "ValidTo" field from the embeddings.
+func extractValidTos(embeddings []models.Embedding) []int64 {
+	times := make([]int64, len(embeddings))
+	for i, embedding := range embeddings {
+		times[i] = embedding.ValidTo
+	}
+	return times
+}
+
+// extractEmbeddingFilenames extracts the "Filename" field from the embeddings.
+func extractEmbeddingFilenames(embeddings []models.Embedding) []string {
+	filenames := make([]string, len(embeddings))
+	for i, embedding := range embeddings {
+		filenames[i] = embedding.Filename
+	}
+	return filenames
+}
+
+// extractEmbeddingLinks extracts the "Link" field from the embeddings.
+func extractEmbeddingLinks(embeddings []models.Embedding) []string {
+	links := make([]string, len(embeddings))
+	for i, embedding := range embeddings {
+		links[i] = embedding.Link
+	}
+	return links
+}
+
+// extractEmbeddingCategories extracts the "Category" field from the embeddings.
+func extractEmbeddingCategories(embeddings []models.Embedding) []string {
+	categories := make([]string, len(embeddings))
+	for i, embedding := range embeddings {
+		categories[i] = embedding.Category
+	}
+	return categories
+}
+
+// extractEmbeddingMetadata extracts the "Metadata" field from the embeddings as JSON objects.
+func extractEmbeddingMetadata(embeddings []models.Embedding) [][]byte {
+	metadata := make([][]byte, len(embeddings))
+	for i, embedding := range embeddings {
+		if embedding.Metadata == nil {
+			metadata[i] = []byte("{}")
+			continue
+		}
+		metadata[i], _ = json.Marshal(embedding.Metadata)
+	}
+	return metadata
+}
+
 func transformResultSet(rs client.ResultSet, outputFields ...string) ([]map[string]interface{}, error) {
 	if rs == nil || rs.Len() == 0 {
 		return nil, fmt.Errorf("empty result set")
@@ -202,6 +296,20 @@ func transformResultSet(rs client.ResultSet, outputFields ...string) ([]map[stri
 				}
 				row[fieldName] = value
 
+			case entity.FieldTypeJSON:
+				value, err := column.Get(i)
+				if err != nil {
+					return nil, fmt.Errorf("error getting json value for column %s, row %d: %w", fieldName, i, err)
+				}
+				row[fieldName] = value
+
+			case entity.FieldTypeFloatVector:
+				value, err := column.Get(i)
+				if err != nil {
+					return nil, fmt.Errorf("error getting vector value for column %s, row %d: %w", fieldName, i, err)
+				}
+				row[fieldName] = value
+
 			default:
 				return nil, fmt.Errorf("unsupported field type for column %s", fieldName)
 			}
@@ -266,6 +374,13 @@ func transformSearchResultSet(rs client.SearchResult, outputFields ...string) ([
 				}
 				result[i][fieldName] = value
 
+			case entity.FieldTypeJSON:
+				value, err := column.Get(i)
+				if err != nil {
+					return nil, fmt.Errorf("error getting json value for column %s, row %d: %w", fieldName, i, err)
+				}
+				result[i][fieldName] = value
+
 			default:
 				return nil, fmt.Errorf("unsupported field type for column %s", fieldName)
 			}
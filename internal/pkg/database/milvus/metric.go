@@ -0,0 +1,26 @@
+package milvus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// ParseMetric returns the metric type named L2, IP or COSINE (case insensitive)
+func ParseMetric(name string) (entity.MetricType, error) {
+	switch metric := entity.MetricType(strings.ToUpper(strings.TrimSpace(name))); metric {
+	case entity.L2, entity.IP, entity.COSINE:
+		return metric, nil
+	}
+	return "", fmt.Errorf("unsupported metric %q, expected L2, IP or COSINE", name)
+}
+
+// similarity converts a raw Milvus score into a similarity where higher is better:
+// COSINE and IP scores already are similarities, L2 distances d become 1/(1+d) in (0, 1].
+func similarity(metric entity.MetricType, score float32) float32 {
+	if metric == entity.L2 {
+		return 1 / (1 + score)
+	}
+	return score
+}
@@ -0,0 +1,39 @@
+package milvus
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+func TestParseMetric(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    entity.MetricType
+		wantErr bool
+	}{
+		{"L2", entity.L2, false},
+		{"cosine", entity.COSINE, false},
+		{" ip ", entity.IP, false},
+		{"HAMMING", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMetric(tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMetric(%q) = %v, %v, want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSimilarityOrdersL2Distances(t *testing.T) {
+	near, far := similarity(entity.L2, 0.2), similarity(entity.L2, 1.5)
+	if near <= far {
+		t.Errorf("similarity(L2) near = %v, far = %v, want the nearer chunk to score higher", near, far)
+	}
+	if got := similarity(entity.L2, 0); got != 1 {
+		t.Errorf("similarity(L2, 0) = %v, want 1", got)
+	}
+	if got := similarity(entity.COSINE, 0.8); got != 0.8 {
+		t.Errorf("similarity(COSINE, 0.8) = %v, want 0.8", got)
+	}
+}
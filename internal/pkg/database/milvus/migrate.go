@@ -0,0 +1,117 @@
+package milvus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// migrationSuffix names the collection being migrated while its rows are copied, Milvus
+// can't add fields to an existing collection
+const migrationSuffix = "_migrating"
+
+// migrationBatch is the number of rows copied at once
+const migrationBatch = 1000
+
+// migrateCollection copies the rows of the source collection into a new collection with the
+// expected schema and drops the source. The fields the source misses get their zero value:
+// no category, metadata, link or filename, and no version for the documents, which is how
+// the documents ingested before versioning are read.
+func (m *Client) migrateCollection(ctx context.Context, schema *entity.Schema, source string) error {
+	collectionName := schema.CollectionName
+
+	coll, err := m.Instance.DescribeCollection(ctx, source)
+	if err != nil {
+		return fmt.Errorf("failed to describe collection '%s': %w", source, err)
+	}
+	existing := make(map[string]bool, len(coll.Schema.Fields))
+	for _, field := range coll.Schema.Fields {
+		existing[field.Name] = true
+	}
+	var fields []string
+	for _, field := range schema.Fields {
+		if existing[field.Name] {
+			fields = append(fields, field.Name)
+		}
+	}
+
+	if err := m.Instance.CreateCollection(ctx, schema, entity.DefaultShardNumber); err != nil {
+		return fmt.Errorf("failed to create collection '%s': %w", collectionName, err)
+	}
+	if err := m.Instance.LoadCollection(ctx, source, false); err != nil {
+		return fmt.Errorf("failed to load collection '%s': %w", source, err)
+	}
+
+	it, err := m.Instance.QueryIterator(ctx, client.NewQueryIteratorOption(source).
+		WithOutputFields(fields...).
+		WithBatchSize(migrationBatch))
+	if err != nil {
+		return fmt.Errorf("failed to query collection '%s': %w", source, err)
+	}
+
+	copied := 0
+	for {
+		rs, err := it.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query collection '%s': %w", source, err)
+		}
+
+		columns, err := migratedColumns(schema, rs)
+		if err != nil {
+			return fmt.Errorf("failed to migrate collection '%s': %w", collectionName, err)
+		}
+		if _, err := m.Instance.Insert(ctx, collectionName, "", columns...); err != nil {
+			return fmt.Errorf("failed to insert into collection '%s': %w", collectionName, err)
+		}
+		copied += rs.Len()
+	}
+
+	if err := m.Instance.Flush(ctx, collectionName, false); err != nil {
+		return fmt.Errorf("failed to flush collection '%s': %w", collectionName, err)
+	}
+	if err := m.Instance.DropCollection(ctx, source); err != nil {
+		return fmt.Errorf("failed to drop collection '%s': %w", source, err)
+	}
+
+	log.Printf("Collection '%s' migrated, %d rows copied", collectionName, copied)
+	return nil
+}
+
+// migratedColumns returns the columns of the schema for the rows of the result set, the
+// fields missing from the result set are filled with their zero value
+func migratedColumns(schema *entity.Schema, rs client.ResultSet) ([]entity.Column, error) {
+	n := rs.Len()
+	columns := make([]entity.Column, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		if column := rs.GetColumn(field.Name); column != nil {
+			columns = append(columns, column)
+			continue
+		}
+
+		switch field.DataType {
+		case entity.FieldTypeVarChar:
+			columns = append(columns, entity.NewColumnVarChar(field.Name, make([]string, n)))
+		case entity.FieldTypeInt64:
+			columns = append(columns, entity.NewColumnInt64(field.Name, make([]int64, n)))
+		case entity.FieldTypeInt32:
+			columns = append(columns, entity.NewColumnInt32(field.Name, make([]int32, n)))
+		case entity.FieldTypeJSON:
+			values := make([][]byte, n)
+			for i := range values {
+				values[i] = []byte("{}")
+			}
+			columns = append(columns, entity.NewColumnJSONBytes(field.Name, values))
+		default:
+			return nil, fmt.Errorf("no value for the %s field", field.Name)
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,57 @@
+package milvus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+func TestMigratedColumns(t *testing.T) {
+	// chunks of a collection created before the validity and document fields
+	rs := client.ResultSet{
+		entity.NewColumnVarChar("ID", []string{"c1", "c2"}),
+		entity.NewColumnVarChar("DocumentID", []string{"d1", "d1"}),
+		entity.NewColumnFloatVector("Vector", 2, [][]float32{{0, 1}, {1, 0}}),
+		entity.NewColumnVarChar("TextChunk", []string{"first", "second"}),
+		entity.NewColumnInt32("Dimension", []int32{2, 2}),
+		entity.NewColumnInt32("Order", []int32{0, 1}),
+	}
+
+	columns, err := migratedColumns(createEmbeddingSchema(2), rs)
+	if err != nil {
+		t.Fatalf("migratedColumns() error = %v", err)
+	}
+
+	schema := createEmbeddingSchema(2)
+	if len(columns) != len(schema.Fields) {
+		t.Fatalf("migratedColumns() = %d columns, want %d", len(columns), len(schema.Fields))
+	}
+	for i, field := range schema.Fields {
+		if columns[i].Name() != field.Name || columns[i].Type() != field.DataType || columns[i].Len() != 2 {
+			t.Errorf("column %d = %s %v of %d rows, want %s %v of 2 rows", i, columns[i].Name(), columns[i].Type(), columns[i].Len(), field.Name, field.DataType)
+		}
+	}
+
+	byName := make(map[string]entity.Column)
+	for _, column := range columns {
+		byName[column.Name()] = column
+	}
+	if byName["TextChunk"] != rs.GetColumn("TextChunk") {
+		t.Error("TextChunk column not copied")
+	}
+	if got := byName["ValidTo"].(*entity.ColumnInt64).Data(); !reflect.DeepEqual(got, []int64{0, 0}) {
+		t.Errorf("ValidTo = %v, want the latest version", got)
+	}
+	if got := byName["Metadata"].(*entity.ColumnJSONBytes).Data(); string(got[0]) != "{}" || string(got[1]) != "{}" {
+		t.Errorf("Metadata = %q, want empty objects", got)
+	}
+}
+
+func TestMigratedColumnsMissingVector(t *testing.T) {
+	rs := client.ResultSet{entity.NewColumnVarChar("ID", []string{"c1"})}
+	if _, err := migratedColumns(createEmbeddingSchema(2), rs); err == nil {
+		t.Error("migratedColumns() error = nil, want the missing vectors rejected")
+	}
+}
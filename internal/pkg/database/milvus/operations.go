@@ -2,8 +2,12 @@ package milvus
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/elchemista/easy_rag/internal/models"
 
@@ -11,8 +15,17 @@ import (
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 )
 
-// InsertDocuments inserts documents into the "documents" collection.
+// ErrNotFound is returned when no document has the requested ID
+var ErrNotFound = errors.New("document not found")
+
+// InsertDocuments inserts documents into the "documents" collection, replacing the
+// documents with the same ID.
 func (m *Client) InsertDocuments(ctx context.Context, docs []models.Document) error {
+	vectors := extractVectorsDocs(docs)
+	if _, err := validateAndConvertVectors(vectors, m.Dim); err != nil {
+		return err
+	}
+
 	idColumn := entity.NewColumnVarChar("ID", extractIDs(docs))
 	contentColumn := entity.NewColumnVarChar("Content", extractContents(docs))
 	linkColumn := entity.NewColumnVarChar("Link", extractLinks(docs))
@@ -21,10 +34,15 @@ func (m *Client) InsertDocuments(ctx context.Context, docs []models.Document) er
 	embeddingModelColumn := entity.NewColumnVarChar("EmbeddingModel", extractEmbeddingModels(docs))
 	summaryColumn := entity.NewColumnVarChar("Summary", extractSummaries(docs))
 	metadataColumn := entity.NewColumnVarChar("Metadata", extractMetadata(docs))
-	vectorColumn := entity.NewColumnFloatVector("Vector", 1024, extractVectorsDocs(docs))
-	// Insert the data
-	_, err := m.Instance.Insert(ctx, "documents", "_default", idColumn, contentColumn, linkColumn, filenameColumn,
-		categoryColumn, embeddingModelColumn, summaryColumn, metadataColumn, vectorColumn)
+	externalIDColumn := entity.NewColumnVarChar("ExternalID", extractExternalIDs(docs))
+	contentHashColumn := entity.NewColumnVarChar("ContentHash", extractContentHashes(docs))
+	versionColumn := entity.NewColumnInt64("Version", extractVersions(docs))
+	updatedAtColumn := entity.NewColumnInt64("UpdatedAt", extractUpdatedAts(docs))
+	vectorColumn := entity.NewColumnFloatVector("Vector", m.Dim, vectors)
+	// Upsert the data, Milvus doesn't enforce unique primary keys on insert
+	_, err := m.Instance.Upsert(ctx, "documents", "_default", idColumn, contentColumn, linkColumn, filenameColumn,
+		categoryColumn, embeddingModelColumn, summaryColumn, metadataColumn, externalIDColumn, contentHashColumn,
+		versionColumn, updatedAtColumn, vectorColumn)
 	if err != nil {
 		return fmt.Errorf("failed to insert documents: %w", err)
 	}
@@ -38,17 +56,33 @@ func (m *Client) InsertDocuments(ctx context.Context, docs []models.Document) er
 	return nil
 }
 
-// InsertEmbeddings inserts embeddings into the "chunks" collection.
+// InsertEmbeddings inserts embeddings into the "chunks" collection, replacing the
+// embeddings with the same ID.
 func (m *Client) InsertEmbeddings(ctx context.Context, embeddings []models.Embedding) error {
+	vectors := extractVectors(embeddings)
+	if _, err := validateAndConvertVectors(vectors, m.Dim); err != nil {
+		return err
+	}
+	if err := validateTextChunks(embeddings); err != nil {
+		return err
+	}
+
 	idColumn := entity.NewColumnVarChar("ID", extractEmbeddingIDs(embeddings))
 	documentIDColumn := entity.NewColumnVarChar("DocumentID", extractDocumentIDs(embeddings))
-	vectorColumn := entity.NewColumnFloatVector("Vector", 1024, extractVectors(embeddings))
+	vectorColumn := entity.NewColumnFloatVector("Vector", m.Dim, vectors)
 	textChunkColumn := entity.NewColumnVarChar("TextChunk", extractTextChunks(embeddings))
 	dimensionColumn := entity.NewColumnInt32("Dimension", extractDimensions(embeddings))
 	orderColumn := entity.NewColumnInt32("Order", extractOrders(embeddings))
+	validFromColumn := entity.NewColumnInt64("ValidFrom", extractValidFroms(embeddings))
+	validToColumn := entity.NewColumnInt64("ValidTo", extractValidTos(embeddings))
+	filenameColumn := entity.NewColumnVarChar("Filename", extractEmbeddingFilenames(embeddings))
+	linkColumn := entity.NewColumnVarChar("Link", extractEmbeddingLinks(embeddings))
+	categoryColumn := entity.NewColumnVarChar("Category", extractEmbeddingCategories(embeddings))
+	metadataColumn := entity.NewColumnJSONBytes("Metadata", extractEmbeddingMetadata(embeddings))
 
-	_, err := m.Instance.Insert(ctx, "chunks", "_default", idColumn, documentIDColumn, vectorColumn,
-		textChunkColumn, dimensionColumn, orderColumn)
+	_, err := m.Instance.Upsert(ctx, "chunks", "_default", idColumn, documentIDColumn, vectorColumn,
+		textChunkColumn, dimensionColumn, orderColumn, validFromColumn, validToColumn, filenameColumn, linkColumn,
+		categoryColumn, metadataColumn)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert embeddings: %w", err)
@@ -65,19 +99,20 @@ func (m *Client) InsertEmbeddings(ctx context.Context, embeddings []models.Embed
 // GetDocumentByID retrieves a document from the "documents" collection by ID.
 func (m *Client) GetDocumentByID(ctx context.Context, id string) (map[string]interface{}, error) {
 	collectionName := "documents"
-	expr := fmt.Sprintf("ID == '%s'", id)
-	projections := []string{"ID", "Content", "Link", "Filename", "Category", "EmbeddingModel", "Summary", "Metadata"} // Fetch all fields
+	expr := fmt.Sprintf("ID == %s", quote(id))
+	projections := documentProjections
 
 	results, err := m.Instance.Query(ctx, collectionName, nil, expr, projections)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query document by ID: %w", err)
 	}
 
-	if len(results) == 0 {
-		return nil, fmt.Errorf("document with ID '%s' not found", id)
+	// the result set has a column per projection even without rows
+	if results.Len() == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 
-	mp, err := transformResultSet(results, "ID", "Content", "Link", "Filename", "Category", "EmbeddingModel", "Summary", "Metadata")
+	mp, err := transformResultSet(results, projections...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
@@ -92,7 +127,7 @@ func (m *Client) GetDocumentByID(ctx context.Context, id string) (map[string]int
 // GetAllDocuments retrieves all documents from the "documents" collection.
 func (m *Client) GetAllDocuments(ctx context.Context) ([]models.Document, error) {
 	collectionName := "documents"
-	projections := []string{"ID", "Content", "Link", "Filename", "Category", "EmbeddingModel", "Summary", "Metadata"} // Fetch all fields
+	projections := documentProjections
 	expr := ""
 
 	rs, err := m.Instance.Query(ctx, collectionName, nil, expr, projections, client.WithLimit(1000))
@@ -104,7 +139,7 @@ func (m *Client) GetAllDocuments(ctx context.Context) ([]models.Document, error)
 		return nil, fmt.Errorf("no documents found in the collection")
 	}
 
-	results, err := transformResultSet(rs, "ID", "Content", "Link", "Filename", "Category", "EmbeddingModel", "Summary", "Metadata")
+	results, err := transformResultSet(rs, projections...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal all documents: %w", err)
@@ -112,26 +147,71 @@ func (m *Client) GetAllDocuments(ctx context.Context) ([]models.Document, error)
 
 	var docs []models.Document = make([]models.Document, len(results))
 	for i, result := range results {
-		docs[i] = models.Document{
-			ID:             result["ID"].(string),
-			Content:        result["Content"].(string),
-			Link:           result["Link"].(string),
-			Filename:       result["Filename"].(string),
-			Category:       result["Category"].(string),
-			EmbeddingModel: result["EmbeddingModel"].(string),
-			Summary:        result["Summary"].(string),
-			Metadata:       convertToMetadata(results[0]["Metadata"].(string)),
-		}
+		docs[i] = toDocument(result)
 	}
 
 	return docs, nil
 }
 
-// GetAllEmbeddingByDocID retrieves all embeddings linked to a specific DocumentID from the "chunks" collection.
+// FindDocuments returns the documents with the external ID, or with the content hash when
+// the external ID is empty.
+func (m *Client) FindDocuments(ctx context.Context, externalID, contentHash string) ([]models.Document, error) {
+	collectionName := "documents"
+	projections := documentProjections
+	expr := fmt.Sprintf("ContentHash == %s", quote(contentHash))
+	if externalID != "" {
+		expr = fmt.Sprintf("ExternalID == %s", quote(externalID))
+	}
+
+	rs, err := m.Instance.Query(ctx, collectionName, nil, expr, projections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+
+	if rs.Len() == 0 {
+		return nil, nil
+	}
+
+	results, err := transformResultSet(rs, projections...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal documents: %w", err)
+	}
+
+	docs := make([]models.Document, len(results))
+	for i, result := range results {
+		docs[i] = toDocument(result)
+	}
+
+	return docs, nil
+}
+
+// documentProjections are the fields of the "documents" collection returned by a query
+var documentProjections = []string{"ID", "Content", "Link", "Filename", "Category", "EmbeddingModel", "Summary", "Metadata",
+	"ExternalID", "ContentHash", "Version", "UpdatedAt"}
+
+// toDocument converts a row of the "documents" collection queried with documentProjections
+func toDocument(result map[string]interface{}) models.Document {
+	return models.Document{
+		ID:             result["ID"].(string),
+		Content:        result["Content"].(string),
+		Link:           result["Link"].(string),
+		Filename:       result["Filename"].(string),
+		Category:       result["Category"].(string),
+		EmbeddingModel: result["EmbeddingModel"].(string),
+		Summary:        result["Summary"].(string),
+		Metadata:       convertToMetadata(result["Metadata"].(string)),
+		ExternalID:     result["ExternalID"].(string),
+		ContentHash:    result["ContentHash"].(string),
+		Version:        result["Version"].(int64),
+		UpdatedAt:      result["UpdatedAt"].(int64),
+	}
+}
+
+// GetAllEmbeddingByDocID retrieves the embeddings of the latest version of a document from the "chunks" collection.
 func (m *Client) GetAllEmbeddingByDocID(ctx context.Context, documentID string) ([]models.Embedding, error) {
 	collectionName := "chunks"
 	projections := []string{"ID", "DocumentID", "TextChunk", "Order"} // Fetch all fields
-	expr := fmt.Sprintf("DocumentID == '%s'", documentID)
+	expr := fmt.Sprintf("DocumentID == %s && %s", quote(documentID), validityExpression(nil))
 
 	rs, err := m.Instance.Query(ctx, collectionName, nil, expr, projections, client.WithLimit(1000))
 
@@ -163,16 +243,244 @@ func (m *Client) GetAllEmbeddingByDocID(ctx context.Context, documentID string)
 	return embeddings, nil
 }
 
-func (m *Client) Search(ctx context.Context, vectors [][]float32, topK int) ([]models.Embedding, error) {
-	const (
-		collectionName = "chunks"
-		vectorDim      = 1024 // Replace with your actual vector dimension
-	)
+// GetDocumentEmbeddings retrieves the embeddings of the latest version of a document from the
+// "chunks" collection, with their vector and the document fields copied on them.
+func (m *Client) GetDocumentEmbeddings(ctx context.Context, documentID string) ([]models.Embedding, error) {
+	collectionName := "chunks"
+	projections := []string{"ID", "DocumentID", "Vector", "TextChunk", "Dimension", "Order", "ValidFrom", "ValidTo",
+		"Filename", "Link", "Category", "Metadata"}
+	expr := fmt.Sprintf("DocumentID == %s && %s", quote(documentID), validityExpression(nil))
+
+	results, err := m.queryPages(ctx, collectionName, expr, projections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embeddings by DocumentID: %w", err)
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	embeddings := make([]models.Embedding, len(results))
+	for i, result := range results {
+		embeddings[i] = models.Embedding{
+			ID:         result["ID"].(string),
+			DocumentID: result["DocumentID"].(string),
+			Vector:     result["Vector"].([]float32),
+			TextChunk:  result["TextChunk"].(string),
+			Dimension:  result["Dimension"].(int64),
+			Order:      result["Order"].(int64),
+			ValidFrom:  result["ValidFrom"].(int64),
+			ValidTo:    result["ValidTo"].(int64),
+			Filename:   result["Filename"].(string),
+			Link:       result["Link"].(string),
+			Category:   result["Category"].(string),
+			Metadata:   convertToMetadata(string(result["Metadata"].([]byte))),
+		}
+	}
+
+	return embeddings, nil
+}
+
+// GetDocumentVector retrieves the vector of a document from the "documents" collection.
+func (m *Client) GetDocumentVector(ctx context.Context, id string) ([]float32, error) {
+	collectionName := "documents"
+	expr := fmt.Sprintf("ID == %s", quote(id))
+
+	rs, err := m.Instance.Query(ctx, collectionName, nil, expr, []string{"Vector"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document vector: %w", err)
+	}
+
+	if rs.Len() == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	results, err := transformResultSet(rs, "Vector")
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document vector: %w", err)
+	}
+
+	return results[0]["Vector"].([]float32), nil
+}
+
+// GetEmbeddingsByOrder retrieves the embeddings of a document with an Order between from and to (inclusive),
+// in the version of the document current at the given time, the latest version if asOf is nil.
+func (m *Client) GetEmbeddingsByOrder(ctx context.Context, documentID string, from, to int64, asOf *time.Time) ([]models.Embedding, error) {
+	collectionName := "chunks"
 	projections := []string{"ID", "DocumentID", "TextChunk", "Order"}
-	metricType := entity.L2 // Default metric type
+	expr := fmt.Sprintf("DocumentID == %s && Order >= %d && Order <= %d && %s", quote(documentID), from, to, validityExpression(asOf))
+
+	results, err := m.queryPages(ctx, collectionName, expr, projections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embeddings by order: %w", err)
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	embeddings := make([]models.Embedding, len(results))
+	for i, result := range results {
+		embeddings[i] = models.Embedding{
+			ID:         result["ID"].(string),
+			DocumentID: result["DocumentID"].(string),
+			TextChunk:  result["TextChunk"].(string),
+			Order:      result["Order"].(int64),
+		}
+	}
+
+	return embeddings, nil
+}
+
+// CountEmbeddings returns the number of embeddings in the "chunks" collection, of every
+// version of the documents.
+func (m *Client) CountEmbeddings(ctx context.Context) (int64, error) {
+	rs, err := m.Instance.Query(ctx, "chunks", nil, "", []string{"count(*)"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count embeddings: %w", err)
+	}
+
+	column := rs.GetColumn("count(*)")
+	if column == nil {
+		return 0, fmt.Errorf("failed to count embeddings: no count returned")
+	}
+	count, err := column.GetAsInt64(0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count embeddings: %w", err)
+	}
+
+	return count, nil
+}
+
+// ScanEmbeddings calls fn with every embedding of the "chunks" collection, of every version of
+// the documents, page by page and without their vector.
+func (m *Client) ScanEmbeddings(ctx context.Context, fn func(embeddings []models.Embedding) error) error {
+	collectionName := "chunks"
+	projections := []string{"ID", "DocumentID", "TextChunk", "Order", "ValidFrom", "ValidTo"}
+
+	err := m.queryEach(ctx, collectionName, "", projections, func(results []map[string]interface{}) error {
+		embeddings := make([]models.Embedding, len(results))
+		for i, result := range results {
+			embeddings[i] = models.Embedding{
+				ID:         result["ID"].(string),
+				DocumentID: result["DocumentID"].(string),
+				TextChunk:  result["TextChunk"].(string),
+				Order:      result["Order"].(int64),
+				ValidFrom:  result["ValidFrom"].(int64),
+				ValidTo:    result["ValidTo"].(int64),
+			}
+		}
+		return fn(embeddings)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan embeddings: %w", err)
+	}
+
+	return nil
+}
+
+// queryPageSize is the number of rows read at once by queryEach
+const queryPageSize = 1000
+
+// queryPages returns every row matching the expression, read page by page. A single query
+// is limited by Milvus to 16384 rows, offset included, so the pages follow the primary key.
+func (m *Client) queryPages(ctx context.Context, collectionName, expr string, projections []string) ([]map[string]interface{}, error) {
+	var results []map[string]interface{}
+	err := m.queryEach(ctx, collectionName, expr, projections, func(page []map[string]interface{}) error {
+		results = append(results, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// queryEach calls fn with the rows matching the expression, one page at a time
+func (m *Client) queryEach(ctx context.Context, collectionName, expr string, projections []string, fn func(page []map[string]interface{}) error) error {
+	it, err := m.Instance.QueryIterator(ctx, client.NewQueryIteratorOption(collectionName).
+		WithExpr(expr).
+		WithOutputFields(projections...).
+		WithBatchSize(queryPageSize))
+	if err != nil {
+		return err
+	}
+
+	for {
+		rs, err := it.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		page, err := transformResultSet(rs, projections...)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal page: %w", err)
+		}
+		if err := fn(page); err != nil {
+			return err
+		}
+	}
+}
+
+// GetEmbeddingsByID retrieves the embeddings with the given IDs matching the filter from the
+// "chunks" collection, without their vector.
+func (m *Client) GetEmbeddingsByID(ctx context.Context, ids []string, filter models.Filter) ([]models.Embedding, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	collectionName := "chunks"
+	projections := searchProjections
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = quote(id)
+	}
+	expr := fmt.Sprintf("ID in [%s] && %s", strings.Join(quoted, ", "), filterExpression(filter))
+
+	rs, err := m.Instance.Query(ctx, collectionName, nil, expr, projections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embeddings by ID: %w", err)
+	}
+
+	if rs.Len() == 0 {
+		return nil, nil
+	}
+
+	results, err := transformResultSet(rs, projections...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal embeddings: %w", err)
+	}
+
+	embeddings := make([]models.Embedding, len(results))
+	for i, result := range results {
+		embeddings[i] = models.Embedding{
+			ID:         result["ID"].(string),
+			DocumentID: result["DocumentID"].(string),
+			TextChunk:  result["TextChunk"].(string),
+			Order:      result["Order"].(int64),
+			ValidFrom:  result["ValidFrom"].(int64),
+			ValidTo:    result["ValidTo"].(int64),
+			Filename:   result["Filename"].(string),
+			Link:       result["Link"].(string),
+			Category:   result["Category"].(string),
+			Metadata:   convertToMetadata(string(result["Metadata"].([]byte))),
+		}
+	}
+
+	return embeddings, nil
+}
+
+// Search returns the topK chunks most similar to the vectors among the chunks matching the filter.
+func (m *Client) Search(ctx context.Context, vectors [][]float32, topK int, filter models.Filter) ([]models.Embedding, error) {
+	const collectionName = "chunks"
+	projections := searchProjections
+	metricType := m.ChunksMetric
 
 	// Validate and convert input vectors
-	searchVectors, err := validateAndConvertVectors(vectors, vectorDim)
+	searchVectors, err := validateAndConvertVectors(vectors, m.Dim)
 	if err != nil {
 		return nil, err
 	}
@@ -184,13 +492,13 @@ func (m *Client) Search(ctx context.Context, vectors [][]float32, topK int) ([]m
 	}
 
 	// Perform the search
-	searchResults, err := m.Instance.Search(ctx, collectionName, nil, "", projections, searchVectors, "Vector", metricType, topK, searchParams, client.WithLimit(10))
+	searchResults, err := m.Instance.Search(ctx, collectionName, nil, filterExpression(filter), projections, searchVectors, "Vector", metricType, topK, searchParams, client.WithLimit(10))
 	if err != nil {
 		return nil, fmt.Errorf("failed to search collection: %w", err)
 	}
 
 	// Process search results
-	embeddings, err := processSearchResults(searchResults)
+	embeddings, err := processSearchResults(searchResults, metricType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process search results: %w", err)
 	}
@@ -198,6 +506,9 @@ func (m *Client) Search(ctx context.Context, vectors [][]float32, topK int) ([]m
 	return embeddings, nil
 }
 
+// searchProjections are the fields of the "chunks" collection returned by a search
+var searchProjections = []string{"ID", "DocumentID", "TextChunk", "Order", "ValidFrom", "ValidTo", "Filename", "Link", "Category", "Metadata"}
+
 // validateAndConvertVectors validates vector dimensions and converts them to Milvus-compatible format.
 func validateAndConvertVectors(vectors [][]float32, expectedDim int) ([]entity.Vector, error) {
 	searchVectors := make([]entity.Vector, len(vectors))
@@ -210,26 +521,47 @@ func validateAndConvertVectors(vectors [][]float32, expectedDim int) ([]entity.V
 	return searchVectors, nil
 }
 
-// processSearchResults transforms and aggregates the search results into embeddings and sorts by score.
-func processSearchResults(results []client.SearchResult) ([]models.Embedding, error) {
+// validateTextChunks checks that the chunks fit in the TextChunk field, Milvus rejects the
+// whole insert otherwise
+func validateTextChunks(embeddings []models.Embedding) error {
+	for _, embedding := range embeddings {
+		if len(embedding.TextChunk) > TextChunkMaxLength {
+			return fmt.Errorf("chunk %d of document %s is %d bytes, more than the %d bytes of the TextChunk field",
+				embedding.Order, embedding.DocumentID, len(embedding.TextChunk), TextChunkMaxLength)
+		}
+	}
+	return nil
+}
+
+// processSearchResults transforms and aggregates the search results into embeddings, converts
+// their scores into similarities (higher is better) and sorts by score.
+func processSearchResults(results []client.SearchResult, metric entity.MetricType) ([]models.Embedding, error) {
 	var embeddings []models.Embedding
 
 	for _, result := range results {
-		for i := 0; i < result.ResultCount; i++ {
-			embeddingMap, err := transformSearchResultSet(result, "ID", "DocumentID", "TextChunk", "Order")
-			if err != nil {
-				return nil, fmt.Errorf("failed to transform search result set: %w", err)
-			}
+		if result.ResultCount == 0 {
+			continue
+		}
 
-			for _, embedding := range embeddingMap {
-				embeddings = append(embeddings, models.Embedding{
-					ID:         embedding["ID"].(string),
-					DocumentID: embedding["DocumentID"].(string),
-					TextChunk:  embedding["TextChunk"].(string),
-					Order:      embedding["Order"].(int64), // Assuming 'Order' is a float64 type
-					Score:      embedding["Score"].(float32),
-				})
-			}
+		embeddingMap, err := transformSearchResultSet(result, searchProjections...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform search result set: %w", err)
+		}
+
+		for _, embedding := range embeddingMap {
+			embeddings = append(embeddings, models.Embedding{
+				ID:         embedding["ID"].(string),
+				DocumentID: embedding["DocumentID"].(string),
+				TextChunk:  embedding["TextChunk"].(string),
+				Order:      embedding["Order"].(int64), // Assuming 'Order' is a float64 type
+				Score:      similarity(metric, embedding["Score"].(float32)),
+				ValidFrom:  embedding["ValidFrom"].(int64),
+				ValidTo:    embedding["ValidTo"].(int64),
+				Filename:   embedding["Filename"].(string),
+				Link:       embedding["Link"].(string),
+				Category:   embedding["Category"].(string),
+				Metadata:   convertToMetadata(string(embedding["Metadata"].([]byte))),
+			})
 		}
 	}
 
@@ -245,7 +577,7 @@ func processSearchResults(results []client.SearchResult) ([]models.Embedding, er
 func (m *Client) DeleteDocument(ctx context.Context, id string) error {
 	collectionName := "documents"
 	partitionName := "_default"
-	expr := fmt.Sprintf("ID == '%s'", id)
+	expr := fmt.Sprintf("ID == %s", quote(id))
 
 	err := m.Instance.Delete(ctx, collectionName, partitionName, expr)
 	if err != nil {
@@ -255,11 +587,33 @@ func (m *Client) DeleteDocument(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteEmbeddingsByID deletes the embeddings with the given IDs from the "chunks" collection.
+func (m *Client) DeleteEmbeddingsByID(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	collectionName := "chunks"
+	partitionName := "_default"
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = quote(id)
+	}
+	expr := fmt.Sprintf("ID in [%s]", strings.Join(quoted, ", "))
+
+	err := m.Instance.Delete(ctx, collectionName, partitionName, expr)
+	if err != nil {
+		return fmt.Errorf("failed to delete embeddings by ID: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteEmbedding deletes an embedding from the "chunks" collection by ID.
 func (m *Client) DeleteEmbedding(ctx context.Context, id string) error {
 	collectionName := "chunks"
 	partitionName := "_default"
-	expr := fmt.Sprintf("DocumentID == '%s'", id)
+	expr := fmt.Sprintf("DocumentID == %s", quote(id))
 
 	err := m.Instance.Delete(ctx, collectionName, partitionName, expr)
 	if err != nil {
@@ -0,0 +1,22 @@
+package milvus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestValidateTextChunks(t *testing.T) {
+	fits := models.Embedding{DocumentID: "doc", TextChunk: strings.Repeat("a", TextChunkMaxLength)}
+	if err := validateTextChunks([]models.Embedding{fits}); err != nil {
+		t.Errorf("validateTextChunks() error = %v, want nil", err)
+	}
+
+	// the limit is in bytes: 40000 two-byte characters do not fit
+	tooLong := models.Embedding{DocumentID: "doc", Order: 3, TextChunk: strings.Repeat("é", 40000)}
+	err := validateTextChunks([]models.Embedding{fits, tooLong})
+	if err == nil || !strings.Contains(err.Error(), "chunk 3 of document doc") {
+		t.Errorf("validateTextChunks() error = %v, want chunk 3 rejected", err)
+	}
+}
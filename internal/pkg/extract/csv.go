@@ -0,0 +1,56 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// CSV extracts one line per record, each value labeled with its column from the header row
+type CSV struct{}
+
+func (CSV) Extract(data []byte) ([]models.Section, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1 // tolerate ragged rows
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	var lines []string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV record: %w", err)
+		}
+
+		var values []string
+		for i, value := range record {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			if i < len(header) && strings.TrimSpace(header[i]) != "" {
+				value = strings.TrimSpace(header[i]) + ": " + value
+			}
+			values = append(values, value)
+		}
+		if len(values) > 0 {
+			lines = append(lines, strings.Join(values, "; ")+".")
+		}
+	}
+
+	return appendSection(nil, models.Section{Text: strings.Join(lines, "\n")}), nil
+}
@@ -0,0 +1,124 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// MaxDOCXDocumentSize is the largest decompressed document of a Word file, a larger one
+// (e.g. a zip bomb) is refused before being read
+const MaxDOCXDocumentSize = 128 << 20 // 128 MiB
+
+// DOCX extracts the paragraphs of a Word document, one section per heading
+type DOCX struct{}
+
+func (DOCX) Extract(data []byte) ([]models.Section, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX archive: %w", err)
+	}
+
+	file, err := archive.Open("word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX document: %w", err)
+	}
+	defer file.Close()
+
+	// the size is the one declared by the archive, reading more than it fails
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX document: %w", err)
+	}
+	if info.Size() > MaxDOCXDocumentSize {
+		return nil, fmt.Errorf("DOCX document is larger than %d bytes once decompressed", MaxDOCXDocumentSize)
+	}
+
+	var sections []models.Section
+	var path headings
+	var current strings.Builder
+
+	// paragraph being read
+	var paragraph strings.Builder
+	level := 0
+
+	decoder := xml.NewDecoder(file)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read DOCX document: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				level = 0
+			case "pStyle":
+				level = headingLevel(attr(t, "val"))
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return nil, fmt.Errorf("failed to read DOCX text: %w", err)
+				}
+				paragraph.WriteString(text)
+			}
+
+		case xml.EndElement:
+			if t.Name.Local != "p" {
+				continue
+			}
+
+			text := strings.TrimSpace(paragraph.String())
+			if level > 0 && text != "" {
+				sections = appendSection(sections, models.Section{Text: current.String(), Heading: path.path()})
+				current.Reset()
+				path = path.push(level, text)
+			}
+			if text != "" {
+				current.WriteString(text + "\n")
+			}
+		}
+	}
+	sections = appendSection(sections, models.Section{Text: current.String(), Heading: path.path()})
+
+	return sections, nil
+}
+
+// headingLevel returns the level of a paragraph style: 1 for "Title" and "Heading1",
+// up to 6 for "Heading6", 0 for the other styles
+func headingLevel(style string) int {
+	style = strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if style == "title" {
+		return 1
+	}
+	if level, err := strconv.Atoi(strings.TrimPrefix(style, "heading")); err == nil && strings.HasPrefix(style, "heading") && level >= 1 && level <= 6 {
+		return level
+	}
+	return 0
+}
+
+// attr returns the value of the attribute with the given local name
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
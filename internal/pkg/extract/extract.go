@@ -0,0 +1,142 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// MIME types of the supported formats
+const (
+	MIMEText     = "text/plain"
+	MIMEMarkdown = "text/markdown"
+	MIMEHTML     = "text/html"
+	MIMECSV      = "text/csv"
+	MIMEPDF      = "application/pdf"
+	MIMEDOCX     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+// ErrUnsupported is returned for files of a type no extractor handles
+var ErrUnsupported = errors.New("unsupported file type")
+
+// Extractor extracts the text of a file format
+type Extractor interface {
+	// Extract returns the text of the file split into sections, keeping their page or heading
+	Extract(data []byte) ([]models.Section, error)
+}
+
+// extensions maps the file extensions to their MIME type, they are trusted before the content
+var extensions = map[string]string{
+	".txt":      MIMEText,
+	".text":     MIMEText,
+	".md":       MIMEMarkdown,
+	".markdown": MIMEMarkdown,
+	".html":     MIMEHTML,
+	".htm":      MIMEHTML,
+	".csv":      MIMECSV,
+	".pdf":      MIMEPDF,
+	".docx":     MIMEDOCX,
+}
+
+// Registry selects the extractor of a file by its MIME type
+type Registry struct {
+	extractors map[string]Extractor
+}
+
+// NewRegistry returns a registry with the extractors of every supported format
+func NewRegistry() *Registry {
+	return &Registry{
+		extractors: map[string]Extractor{
+			MIMEText:     Text{},
+			MIMEMarkdown: Markdown{},
+			MIMEHTML:     HTML{},
+			MIMECSV:      CSV{},
+			MIMEPDF:      PDF{},
+			MIMEDOCX:     DOCX{},
+		},
+	}
+}
+
+// Register sets the extractor of a MIME type, replacing the existing one
+func (r *Registry) Register(mimeType string, extractor Extractor) {
+	r.extractors[mimeType] = extractor
+}
+
+// Detect returns the MIME type of a file from its extension, the declared Content-Type
+// or its content, in this order
+func (r *Registry) Detect(filename string, declared string, data []byte) string {
+	if mimeType, ok := extensions[strings.ToLower(filepath.Ext(filename))]; ok {
+		return mimeType
+	}
+
+	if mimeType, _, err := mime.ParseMediaType(declared); err == nil {
+		if _, ok := r.extractors[mimeType]; ok {
+			return mimeType
+		}
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	return mimeType
+}
+
+// Extract detects the type of the file and extracts its sections
+func (r *Registry) Extract(filename string, declared string, data []byte) (string, []models.Section, error) {
+	mimeType := r.Detect(filename, declared, data)
+
+	extractor, ok := r.extractors[mimeType]
+	if !ok {
+		return mimeType, nil, fmt.Errorf("%w: %s", ErrUnsupported, mimeType)
+	}
+
+	sections, err := extractor.Extract(data)
+	if err != nil {
+		return mimeType, nil, fmt.Errorf("failed to extract %s: %w", filename, err)
+	}
+
+	return mimeType, sections, nil
+}
+
+// Content joins the text of the sections
+func Content(sections []models.Section) string {
+	texts := make([]string, len(sections))
+	for i, section := range sections {
+		texts[i] = section.Text
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// headings tracks the heading path of a document while it is read
+type headings []string
+
+// push sets the heading of a level (1 to 6), dropping the deeper ones
+func (h headings) push(level int, title string) headings {
+	for len(h) < level-1 {
+		h = append(h, "")
+	}
+	return append(h[:level-1], title)
+}
+
+// path joins the non-empty headings, e.g. "Installation > Linux"
+func (h headings) path() string {
+	var titles []string
+	for _, title := range h {
+		if title != "" {
+			titles = append(titles, title)
+		}
+	}
+	return strings.Join(titles, " > ")
+}
+
+// appendSection adds a section unless its text is blank
+func appendSection(sections []models.Section, section models.Section) []models.Section {
+	section.Text = strings.TrimSpace(section.Text)
+	if section.Text == "" {
+		return sections
+	}
+	return append(sections, section)
+}
@@ -0,0 +1,173 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestDetect(t *testing.T) {
+	r := NewRegistry()
+	tests := []struct {
+		filename string
+		declared string
+		data     string
+		want     string
+	}{
+		{"notes.md", "application/octet-stream", "# Title", MIMEMarkdown},
+		{"REPORT.PDF", "", "%PDF-1.4", MIMEPDF},
+		{"upload", "text/csv; charset=utf-8", "a,b", MIMECSV},
+		{"upload", "", "<html><body>Hi</body></html>", MIMEHTML},
+		{"upload", "", "plain words", MIMEText},
+	}
+	for _, tt := range tests {
+		if got := r.Detect(tt.filename, tt.declared, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q, %q) = %s, want %s", tt.filename, tt.declared, got, tt.want)
+		}
+	}
+}
+
+func TestExtractUnsupported(t *testing.T) {
+	_, _, err := NewRegistry().Extract("image.png", "", []byte("\x89PNG\r\n\x1a\n"))
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Extract() error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	data := "Intro text.\n\n# Install\n\nRun it.\n\n## Linux\n\n```sh\n# not a heading\n```\n\n# Usage\n\nCall it.\n"
+	sections, err := Markdown{}.Extract([]byte(data))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	var headings []string
+	for _, section := range sections {
+		headings = append(headings, section.Heading)
+	}
+	if want := []string{"", "Install", "Install > Linux", "Usage"}; !reflect.DeepEqual(headings, want) {
+		t.Errorf("headings = %q, want %q", headings, want)
+	}
+	if !strings.Contains(sections[2].Text, "# not a heading") {
+		t.Errorf("code block section = %q, want the code kept", sections[2].Text)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	data := `<html><head><title>T</title><style>p{}</style></head><body>
+		<h1>Guide</h1><p>First   paragraph.</p><script>alert(1)</script>
+		<h2>Details</h2><ul><li>One</li><li>Two</li></ul></body></html>`
+	sections, err := HTML{}.Extract([]byte(data))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	want := []models.Section{
+		{Text: "Guide\nFirst paragraph.", Heading: "Guide"},
+		{Text: "Details\nOne\nTwo", Heading: "Guide > Details"},
+	}
+	if !reflect.DeepEqual(sections, want) {
+		t.Errorf("Extract() = %q, want %q", sections, want)
+	}
+}
+
+func TestReadableHTML(t *testing.T) {
+	data := `<html><body>
+		<header><a href="/">Home</a></header>
+		<nav><ul><li>Docs</li><li>Blog</li></ul></nav>
+		<div role="navigation">Breadcrumbs</div>
+		<main><h1>Guide</h1><p>Content.</p><aside>Related posts</aside>
+		<form><button>Subscribe</button></form></main>
+		<footer>Copyright</footer></body></html>`
+
+	readable, err := HTML{Readable: true}.Extract([]byte(data))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	want := []models.Section{{Text: "Guide\nContent.", Heading: "Guide"}}
+	if !reflect.DeepEqual(readable, want) {
+		t.Errorf("Extract() = %q, want %q", readable, want)
+	}
+
+	// without a <main> the whole body is kept, still without the boilerplate
+	readable, err = HTML{Readable: true}.Extract([]byte(`<body><nav>Menu</nav><p>Text.</p><footer>End</footer></body>`))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if want := []models.Section{{Text: "Text."}}; !reflect.DeepEqual(readable, want) {
+		t.Errorf("Extract() = %q, want %q", readable, want)
+	}
+
+	all, err := HTML{}.Extract([]byte(data))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if !strings.Contains(Content(all), "Copyright") {
+		t.Errorf("Extract() without Readable dropped the footer: %q", all)
+	}
+}
+
+func TestDOCXTooLarge(t *testing.T) {
+	// a small entry declaring a huge decompressed size, like a zip bomb
+	content := []byte("<w:document/>")
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	file, err := archive.CreateRaw(&zip.FileHeader{
+		Name:               "word/document.xml",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(content),
+		CompressedSize64:   uint64(len(content)),
+		UncompressedSize64: MaxDOCXDocumentSize + 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(content)
+	archive.Close()
+
+	if _, err := (DOCX{}).Extract(buf.Bytes()); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Extract() error = %v, want the document refused", err)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	sections, err := CSV{}.Extract([]byte("name,role\nAda,engineer\nBob,\n"))
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if len(sections) != 1 || sections[0].Text != "name: Ada; role: engineer.\nname: Bob." {
+		t.Errorf("Extract() = %q", sections)
+	}
+}
+
+func TestDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	file, _ := archive.Create("word/document.xml")
+	file.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Preface.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Scope</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">This standard </w:t></w:r><w:r><w:t>applies.</w:t></w:r></w:p>
+</w:body></w:document>`))
+	archive.Close()
+
+	sections, err := DOCX{}.Extract(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	want := []models.Section{
+		{Text: "Preface."},
+		{Text: "Scope\nThis standard applies.", Heading: "Scope"},
+	}
+	if !reflect.DeepEqual(sections, want) {
+		t.Errorf("Extract() = %q, want %q", sections, want)
+	}
+}
@@ -0,0 +1,215 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTML extracts the visible text of a page, one section per heading
+type HTML struct {
+	// Readable drops the navigation, header, footer, sidebars and forms of the page and
+	// keeps only its <main> or <article> when it has one, used for pages fetched from the web
+	Readable bool
+}
+
+// skipped are the elements without readable text
+var skipped = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Head:     true,
+}
+
+// blocks are the elements starting a new line
+var blocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.Section: true, atom.Article: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Table: true, atom.Ul: true, atom.Ol: true, atom.Dd: true, atom.Dt: true,
+}
+
+// boilerplate are the elements dropped from readable pages
+var boilerplate = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Header: true,
+	atom.Footer: true,
+	atom.Aside:  true,
+	atom.Form:   true,
+	atom.Menu:   true,
+	atom.Dialog: true,
+	atom.Button: true,
+	atom.Iframe: true,
+}
+
+// boilerplateRoles are the ARIA roles of the elements dropped from readable pages
+var boilerplateRoles = map[string]bool{
+	"navigation":    true,
+	"banner":        true,
+	"contentinfo":   true,
+	"complementary": true,
+	"search":        true,
+	"dialog":        true,
+}
+
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+func (h HTML) Extract(data []byte) ([]models.Section, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	w := &htmlWalker{readable: h.Readable}
+	root := doc
+	if h.Readable {
+		if main := findMain(doc); main != nil {
+			root = main
+		}
+	}
+	w.walk(root)
+	w.flush()
+
+	return w.sections, nil
+}
+
+// htmlWalker collects the text of the nodes into sections
+type htmlWalker struct {
+	sections []models.Section
+	path     headings
+	current  strings.Builder
+	readable bool
+}
+
+func (w *htmlWalker) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.current.WriteString(collapseSpaces(n.Data))
+		return
+	case html.ElementNode:
+		if skipped[n.DataAtom] || w.readable && isBoilerplate(n) {
+			return
+		}
+		if level, ok := headingLevels[n.DataAtom]; ok {
+			w.flush()
+			title := strings.TrimSpace(collapseSpaces(nodeText(n)))
+			w.path = w.path.push(level, title)
+			w.current.WriteString(title + "\n")
+			return
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+
+	if n.Type == html.ElementNode && blocks[n.DataAtom] {
+		w.current.WriteString("\n")
+	}
+}
+
+func (w *htmlWalker) flush() {
+	w.sections = appendSection(w.sections, models.Section{Text: cleanLines(w.current.String()), Heading: w.path.path()})
+	w.current.Reset()
+}
+
+// isBoilerplate reports whether the element is navigation or page chrome rather than content
+func isBoilerplate(n *html.Node) bool {
+	if boilerplate[n.DataAtom] || boilerplateRoles[strings.ToLower(htmlAttr(n, "role"))] {
+		return true
+	}
+	return strings.EqualFold(htmlAttr(n, "aria-hidden"), "true") || hasHTMLAttr(n, "hidden")
+}
+
+// findMain returns the <main> element of the page, or its first <article>, nil if it has neither
+func findMain(doc *html.Node) *html.Node {
+	if main := findElement(doc, func(n *html.Node) bool {
+		return n.DataAtom == atom.Main || strings.EqualFold(htmlAttr(n, "role"), "main")
+	}); main != nil {
+		return main
+	}
+	return findElement(doc, func(n *html.Node) bool { return n.DataAtom == atom.Article })
+}
+
+// findElement returns the first element matching the predicate, depth first
+func findElement(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// htmlAttr returns the value of an attribute of the element
+func htmlAttr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasHTMLAttr reports whether the element has the attribute, whatever its value
+func hasHTMLAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeText returns the text of a node and its children
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
+
+// collapseSpaces replaces runs of whitespace with a single space, as browsers render them
+func collapseSpaces(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		if s == "" {
+			return ""
+		}
+		return " "
+	}
+
+	collapsed := strings.Join(fields, " ")
+	if strings.TrimLeft(s[:1], " \t\n\r") == "" {
+		collapsed = " " + collapsed
+	}
+	if strings.TrimRight(s[len(s)-1:], " \t\n\r") == "" {
+		collapsed += " "
+	}
+	return collapsed
+}
+
+// cleanLines trims the lines and drops the blank ones
+func cleanLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,42 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/ledongthuc/pdf"
+)
+
+// PDF extracts the text layer of a PDF, one section per page. Scanned pages without
+// text are skipped, they would need OCR.
+type PDF struct{}
+
+func (PDF) Extract(data []byte) (sections []models.Section, err error) {
+	// the parser panics on some malformed files
+	defer func() {
+		if rec := recover(); rec != nil {
+			sections, err = nil, fmt.Errorf("failed to parse PDF: %v", rec)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", i, err)
+		}
+		sections = appendSection(sections, models.Section{Text: text, Page: i})
+	}
+
+	return sections, nil
+}
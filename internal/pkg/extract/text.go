@@ -0,0 +1,60 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// markdownHeading matches ATX headings: "# Title" to "###### Title"
+var markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+
+// Text extracts plain text as a single section
+type Text struct{}
+
+func (Text) Extract(data []byte) ([]models.Section, error) {
+	return appendSection(nil, models.Section{Text: validUTF8(data)}), nil
+}
+
+// Markdown extracts one section per heading, the markup itself is kept
+type Markdown struct{}
+
+func (Markdown) Extract(data []byte) ([]models.Section, error) {
+	var sections []models.Section
+	var path headings
+	var current strings.Builder
+	inCode := false
+
+	flush := func() {
+		sections = appendSection(sections, models.Section{Text: current.String(), Heading: path.path()})
+		current.Reset()
+	}
+
+	for _, line := range strings.Split(validUTF8(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+		}
+
+		if match := markdownHeading.FindStringSubmatch(line); match != nil && !inCode {
+			flush()
+			path = path.push(len(match[1]), match[2])
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+
+	return sections, nil
+}
+
+// validUTF8 returns the data as a string, replacing invalid UTF-8 sequences
+func validUTF8(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
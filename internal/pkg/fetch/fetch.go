@@ -0,0 +1,235 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/extract"
+)
+
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultMaxBytes       = 10 << 20 // 10 MiB
+	DefaultMaxSitemapURLs = 500
+
+	userAgent    = "easy_rag (+https://github.com/elchemista/easy_rag)"
+	maxRedirects = 10
+)
+
+// ErrTooLarge is returned for responses larger than the MaxBytes of the fetcher
+var ErrTooLarge = errors.New("response is too large")
+
+// ErrBlockedAddress is returned for URLs resolving to an internal address that is not allowed
+var ErrBlockedAddress = errors.New("internal address not allowed")
+
+// Page is a fetched web page and its extracted text
+type Page struct {
+	URL         string // Final URL, after the redirects
+	Filename    string // Last segment of the URL path, or its host
+	ContentType string // Detected MIME type
+	FetchedAt   time.Time
+	Sections    []models.Section
+}
+
+// Fetcher downloads web pages and extracts their readable text. The client of NewFetcher
+// refuses to connect to loopback, private, link-local and other internal addresses, on the
+// first request and on every redirect, unless they are in AllowedNetworks.
+type Fetcher struct {
+	Client          *http.Client
+	MaxBytes        int64             // Largest response body read
+	MaxSitemapURLs  int               // Most page URLs returned from a sitemap
+	Extractors      *extract.Registry // HTML is extracted in readable mode, without the page boilerplate
+	AllowedNetworks []netip.Prefix    // Internal networks the fetcher may connect to
+}
+
+// NewFetcher returns a fetcher with the given request timeout and response size limit
+func NewFetcher(timeout time.Duration, maxBytes int64) *Fetcher {
+	extractors := extract.NewRegistry()
+	extractors.Register(extract.MIMEHTML, extract.HTML{Readable: true})
+
+	f := &Fetcher{
+		MaxBytes:       maxBytes,
+		MaxSitemapURLs: DefaultMaxSitemapURLs,
+		Extractors:     extractors,
+	}
+
+	// the address is checked once resolved, right before connecting, so a host can't resolve
+	// to a public address when validated and to an internal one when dialed. Proxies from the
+	// environment would be dialed instead of the page host, they are not used.
+	dialer := &net.Dialer{Timeout: timeout, Control: f.checkAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	f.Client = &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return ValidateURL(req.URL.String())
+		},
+	}
+	return f
+}
+
+// ParseNetworks parses a comma-separated list of CIDR networks or IP addresses
+func ParseNetworks(list string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q: %w", item, err)
+			}
+			networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		network, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", item, err)
+		}
+		networks = append(networks, network.Masked())
+	}
+	return networks, nil
+}
+
+// checkAddress is the dialer Control of the client, it fails for internal addresses that are
+// not in AllowedNetworks
+func (f *Fetcher) checkAddress(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	addr = addr.Unmap()
+
+	if !internal(addr) {
+		return nil
+	}
+	for _, allowed := range f.AllowedNetworks {
+		if allowed.Contains(addr) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, not routable on the internet
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// internal reports whether the address is not a public internet address: loopback, private,
+// link-local (like the 169.254.169.254 metadata service), unspecified or multicast
+func internal(addr netip.Addr) bool {
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr)
+}
+
+// ValidateURL checks that the URL is an absolute http or https URL
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL %q: only http and https are supported", rawURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+	return nil
+}
+
+// Fetch downloads the page and extracts its text according to its type
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Page, error) {
+	resp, data, err := f.get(ctx, rawURL)
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{
+		URL:       resp.Request.URL.String(),
+		Filename:  filename(resp.Request.URL),
+		FetchedAt: time.Now().UTC(),
+	}
+
+	mimeType, sections, err := f.Extractors.Extract(page.Filename, resp.Header.Get("Content-Type"), data)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed to extract %s: %w", page.URL, err)
+	}
+	if len(sections) == 0 {
+		return Page{}, fmt.Errorf("no text found at %s", page.URL)
+	}
+
+	page.ContentType = mimeType
+	page.Sections = sections
+	return page, nil
+}
+
+// get downloads the URL, failing on non-2xx statuses and bodies larger than MaxBytes
+func (f *Fetcher) get(ctx context.Context, rawURL string) (*http.Response, []byte, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request for %s: %w", rawURL, err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, nil, fmt.Errorf("failed to fetch %s: status %s", rawURL, resp.Status)
+	}
+	if f.MaxBytes > 0 && resp.ContentLength > f.MaxBytes {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w (%d bytes)", rawURL, ErrTooLarge, resp.ContentLength)
+	}
+
+	body := io.Reader(resp.Body)
+	if f.MaxBytes > 0 {
+		// one more byte tells a body at the limit from a larger one
+		body = io.LimitReader(resp.Body, f.MaxBytes+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if f.MaxBytes > 0 && int64(len(data)) > f.MaxBytes {
+		return nil, nil, fmt.Errorf("failed to fetch %s: %w (more than %d bytes)", rawURL, ErrTooLarge, f.MaxBytes)
+	}
+
+	return resp, data, nil
+}
+
+// filename returns the last segment of the URL path, or the host for the root page
+func filename(u *url.URL) string {
+	if base := path.Base(u.Path); base != "/" && base != "." {
+		return base
+	}
+	return u.Host
+}
@@ -0,0 +1,190 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const article = `<html><head><title>Guide</title></head><body>
+	<nav><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+	<main><h1>Guide</h1><p>Install the package.</p></main>
+	<footer>Copyright</footer></body></html>`
+
+// loopback lets the fetchers of the tests reach the test servers
+var loopback = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/docs/guide.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, article)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs/guide.html", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		// chunked, without a Content-Length
+		for i := 0; i < 10; i++ {
+			fmt.Fprint(w, strings.Repeat("x", 100))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>http://%[1]s/pages.xml</loc></sitemap>
+  <sitemap><loc>http://%[1]s/posts.xml.gz</loc></sitemap>
+</sitemapindex>`, r.Host)
+	})
+	mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>http://%[1]s/docs/guide.html</loc></url>
+  <url><loc> http://%[1]s/about </loc></url>
+  <url><loc>http://%[1]s/docs/guide.html</loc></url>
+  <url><loc>ftp://%[1]s/file</loc></url>
+</urlset>`, r.Host)
+	})
+	mux.HandleFunc("/posts.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		fmt.Fprintf(gz, `<urlset><url><loc>http://%s/posts/1</loc></url></urlset>`, r.Host)
+		gz.Close()
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Write(buf.Bytes())
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetch(t *testing.T) {
+	server := newServer(t)
+	fetcher := NewFetcher(DefaultTimeout, DefaultMaxBytes)
+	fetcher.AllowedNetworks = loopback
+
+	page, err := fetcher.Fetch(context.Background(), server.URL+"/old")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if page.URL != server.URL+"/docs/guide.html" {
+		t.Errorf("URL = %s, want the redirect target", page.URL)
+	}
+	if page.Filename != "guide.html" || page.ContentType != "text/html" {
+		t.Errorf("Filename = %s, ContentType = %s", page.Filename, page.ContentType)
+	}
+	if page.FetchedAt.IsZero() {
+		t.Error("FetchedAt is not set")
+	}
+	if len(page.Sections) != 1 || page.Sections[0].Text != "Guide\nInstall the package." {
+		t.Errorf("Sections = %q, want the main content only", page.Sections)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	server := newServer(t)
+
+	fetcher := NewFetcher(100*time.Millisecond, 500)
+	fetcher.AllowedNetworks = loopback
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"not found", server.URL + "/missing", "404"},
+		{"too large", server.URL + "/large", ErrTooLarge.Error()},
+		{"timeout", server.URL + "/slow", "Client.Timeout"},
+		{"scheme", "file:///etc/passwd", "only http and https"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fetcher.Fetch(context.Background(), tt.url)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Fetch() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err := fetcher.Fetch(context.Background(), server.URL+"/large")
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Fetch() error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestFetchInternalAddresses(t *testing.T) {
+	server := newServer(t)
+	fetcher := NewFetcher(DefaultTimeout, DefaultMaxBytes)
+
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/docs/guide.html"); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Fetch() of a loopback address error = %v, want ErrBlockedAddress", err)
+	}
+
+	// an allowed host can't redirect to the metadata service
+	fetcher.AllowedNetworks = loopback
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/metadata"); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Fetch() redirected to a link-local address error = %v, want ErrBlockedAddress", err)
+	}
+
+	networks, err := ParseNetworks(" 10.0.0.0/8, 192.168.1.7,fd00::/8,")
+	if err != nil {
+		t.Fatalf("ParseNetworks() error = %v", err)
+	}
+	want := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("192.168.1.7/32"), netip.MustParsePrefix("fd00::/8")}
+	if !reflect.DeepEqual(networks, want) {
+		t.Errorf("ParseNetworks() = %v, want %v", networks, want)
+	}
+	if _, err := ParseNetworks("intranet"); err == nil {
+		t.Error("ParseNetworks() of a host name returned no error")
+	}
+}
+
+func TestSitemap(t *testing.T) {
+	server := newServer(t)
+	fetcher := NewFetcher(DefaultTimeout, DefaultMaxBytes)
+	fetcher.AllowedNetworks = loopback
+
+	urls, err := fetcher.Sitemap(context.Background(), server.URL+"/sitemap.xml")
+	if err != nil {
+		t.Fatalf("Sitemap() error = %v", err)
+	}
+	want := []string{server.URL + "/docs/guide.html", server.URL + "/about", server.URL + "/posts/1"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("Sitemap() = %q, want %q", urls, want)
+	}
+
+	fetcher.MaxSitemapURLs = 2
+	urls, err = fetcher.Sitemap(context.Background(), server.URL+"/sitemap.xml")
+	if err != nil {
+		t.Fatalf("Sitemap() error = %v", err)
+	}
+	if !reflect.DeepEqual(urls, want[:2]) {
+		t.Errorf("Sitemap() with MaxSitemapURLs = 2 returned %q", urls)
+	}
+
+	if _, err := fetcher.Sitemap(context.Background(), server.URL+"/docs/guide.html"); err == nil {
+		t.Error("Sitemap() of an HTML page returned no error")
+	}
+}
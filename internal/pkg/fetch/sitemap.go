@@ -0,0 +1,117 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sitemap is either a <urlset> of pages or a <sitemapindex> of other sitemaps
+type sitemap struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// Sitemap returns the page URLs listed by a sitemap, following sitemap indexes, up to
+// MaxSitemapURLs. Gzipped sitemaps are supported.
+func (f *Fetcher) Sitemap(ctx context.Context, rawURL string) ([]string, error) {
+	var urls []string
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	queue := []string{rawURL}
+	for len(queue) > 0 && !f.sitemapFull(urls) {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if visited[sitemapURL] {
+			continue
+		}
+		visited[sitemapURL] = true
+
+		parsed, err := f.sitemap(ctx, sitemapURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, loc := range parsed.URLs {
+			page := strings.TrimSpace(loc.Loc)
+			if page == "" || seen[page] || ValidateURL(page) != nil {
+				continue
+			}
+			seen[page] = true
+			urls = append(urls, page)
+			if f.sitemapFull(urls) {
+				break
+			}
+		}
+		for _, loc := range parsed.Sitemaps {
+			if child := strings.TrimSpace(loc.Loc); child != "" {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	return urls, nil
+}
+
+// sitemapFull reports whether MaxSitemapURLs is reached, 0 is unlimited
+func (f *Fetcher) sitemapFull(urls []string) bool {
+	return f.MaxSitemapURLs > 0 && len(urls) >= f.MaxSitemapURLs
+}
+
+// sitemap downloads and parses a single sitemap
+func (f *Fetcher) sitemap(ctx context.Context, rawURL string) (sitemap, error) {
+	_, data, err := f.get(ctx, rawURL)
+	if err != nil {
+		return sitemap{}, err
+	}
+
+	// gzip magic number, whatever the extension and Content-Type say
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		data, err = f.gunzip(data)
+		if err != nil {
+			return sitemap{}, fmt.Errorf("failed to decompress sitemap %s: %w", rawURL, err)
+		}
+	}
+
+	var parsed sitemap
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return sitemap{}, fmt.Errorf("failed to parse sitemap %s: %w", rawURL, err)
+	}
+	if parsed.XMLName.Local != "urlset" && parsed.XMLName.Local != "sitemapindex" {
+		return sitemap{}, fmt.Errorf("failed to parse sitemap %s: unexpected root element <%s>", rawURL, parsed.XMLName.Local)
+	}
+
+	return parsed, nil
+}
+
+// gunzip decompresses the data, the decompressed size is limited to MaxBytes too
+func (f *Fetcher) gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	body := io.Reader(reader)
+	if f.MaxBytes > 0 {
+		body = io.LimitReader(reader, f.MaxBytes+1)
+	}
+	decompressed, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if f.MaxBytes > 0 && int64(len(decompressed)) > f.MaxBytes {
+		return nil, ErrTooLarge
+	}
+	return decompressed, nil
+}
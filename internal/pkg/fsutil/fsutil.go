@@ -0,0 +1,55 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to the file at path, replacing it atomically: the data is
+// written and synced to a temporary file of the same directory, which is then renamed over
+// path. A crash leaves either the previous file or the new one, never a partial file.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	file, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmp := file.Name()
+
+	if err := write(file, data, perm); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	// the rename is durable once the directory is synced, not every platform supports it
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// write writes the data to the file, syncs and closes it
+func write(file *os.File, data []byte, perm os.FileMode) error {
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", file.Name(), err)
+	}
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to set the mode of %s: %w", file.Name(), err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync %s: %w", file.Name(), err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", file.Name(), err)
+	}
+	return nil
+}
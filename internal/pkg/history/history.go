@@ -0,0 +1,178 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/pkg/fsutil"
+)
+
+// ErrNotFound is returned when a document has no record of the requested version
+var ErrNotFound = errors.New("version not found")
+
+// Version records one content of a document
+type Version struct {
+	Version     int64     `json:"version"`           // Version number of the document, starts at 1
+	CreatedAt   time.Time `json:"created_at"`        // When the content became the current version
+	ContentHash string    `json:"content_hash"`      // SHA-256 of the content
+	Summary     string    `json:"summary"`           // Summary generated for the content
+	Content     string    `json:"content,omitempty"` // Full text of the version, left out of List
+}
+
+// Store keeps the versions of each document, persisted as one JSON file per document
+type Store struct {
+	mu       sync.Mutex
+	dir      string
+	versions map[string][]Version // used when dir is empty
+}
+
+// NewStore creates a store persisting the versions in dir. An empty dir keeps the versions
+// in memory only.
+func NewStore(dir string) (*Store, error) {
+	s := &Store{
+		dir:      dir,
+		versions: make(map[string][]Version),
+	}
+
+	if dir == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	return s, nil
+}
+
+// Save records a version of the document, replacing the record with the same version number
+func (s *Store) Save(documentID string, version Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, err := s.load(documentID)
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range versions {
+		if versions[i].Version == version.Version {
+			versions[i] = version
+			replaced = true
+		}
+	}
+	if !replaced {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return s.persist(documentID, versions)
+}
+
+// List returns the versions of the document without their content, oldest first
+func (s *Store) List(documentID string) ([]Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, err := s.load(documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make([]Version, len(versions))
+	for i, version := range versions {
+		version.Content = ""
+		listed[i] = version
+	}
+	return listed, nil
+}
+
+// Get returns the given version of the document with its content
+func (s *Store) Get(documentID string, number int64) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions, err := s.load(documentID)
+	if err != nil {
+		return Version{}, err
+	}
+
+	for _, version := range versions {
+		if version.Version == number {
+			return version, nil
+		}
+	}
+	return Version{}, ErrNotFound
+}
+
+// Delete removes every version of the document
+func (s *Store) Delete(documentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.dir == "" {
+		delete(s.versions, documentID)
+		return nil
+	}
+
+	err := os.Remove(filepath.Join(s.dir, fileName(documentID)))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete history of document %s: %w", documentID, err)
+	}
+	return nil
+}
+
+// load reads the versions of the document, none when it has no history
+func (s *Store) load(documentID string) ([]Version, error) {
+	if s.dir == "" {
+		return append([]Version(nil), s.versions[documentID]...), nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, fileName(documentID)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of document %s: %w", documentID, err)
+	}
+
+	var versions []Version
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal history of document %s: %w", documentID, err)
+	}
+	return versions, nil
+}
+
+// persist writes the versions of the document, replacing the previous file atomically
+func (s *Store) persist(documentID string, versions []Version) error {
+	if s.dir == "" {
+		s.versions[documentID] = versions
+		return nil
+	}
+
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history of document %s: %w", documentID, err)
+	}
+
+	if err := fsutil.WriteFileAtomic(filepath.Join(s.dir, fileName(documentID)), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+
+	return nil
+}
+
+// fileName keeps document ids from escaping the history directory
+func fileName(id string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(id) + ".json"
+}
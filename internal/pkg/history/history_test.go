@@ -0,0 +1,53 @@
+package history
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	for _, dir := range []string{"", t.TempDir()} {
+		store, err := NewStore(dir)
+		if err != nil {
+			t.Fatalf("NewStore() error = %v", err)
+		}
+
+		created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		store.Save("doc", Version{Version: 2, CreatedAt: created.Add(time.Hour), ContentHash: "b", Content: "second"})
+		store.Save("doc", Version{Version: 1, CreatedAt: created, ContentHash: "a", Content: "first"})
+		// a replaced version overwrites its record
+		if err := store.Save("doc", Version{Version: 2, CreatedAt: created.Add(time.Hour), ContentHash: "c", Content: "third"}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+
+		versions, err := store.List("doc")
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(versions) != 2 || versions[0].Version != 1 || versions[1].ContentHash != "c" {
+			t.Fatalf("List() = %+v, want versions 1 and 2 with hash c", versions)
+		}
+		if versions[0].Content != "" {
+			t.Errorf("List() content = %q, want none", versions[0].Content)
+		}
+
+		version, err := store.Get("doc", 2)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if version.Content != "third" || !version.CreatedAt.Equal(created.Add(time.Hour)) {
+			t.Errorf("Get() = %+v, want the third content", version)
+		}
+		if _, err := store.Get("doc", 3); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get() missing error = %v, want ErrNotFound", err)
+		}
+
+		if err := store.Delete("doc"); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if versions, _ := store.List("doc"); len(versions) != 0 {
+			t.Errorf("List() after Delete = %+v, want none", versions)
+		}
+	}
+}
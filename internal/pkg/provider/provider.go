@@ -0,0 +1,229 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/elchemista/easy_rag/config"
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/embeddings"
+	"github.com/elchemista/easy_rag/internal/llm"
+	"github.com/elchemista/easy_rag/internal/rerank"
+)
+
+// LLMFactory builds an LLMService from the configuration
+type LLMFactory func(cfg config.Config) (llm.LLMService, error)
+
+// EmbeddingsFactory builds an EmbeddingsService from the configuration
+type EmbeddingsFactory func(cfg config.Config) (embeddings.EmbeddingsService, error)
+
+// RerankerFactory builds a Reranker from the configuration, service is the configured LLM
+type RerankerFactory func(cfg config.Config, service llm.LLMService) (rerank.Reranker, error)
+
+// DatabaseFactory builds a Database from the configuration, storing vectors of the given dimension
+type DatabaseFactory func(ctx context.Context, cfg config.Config, dim int) (database.Database, error)
+
+var (
+	llmProviders = map[string]LLMFactory{
+		"ollama": newOllamaLLM,
+		"openai": newOpenAILLM,
+	}
+	embeddingsProviders = map[string]EmbeddingsFactory{
+		"ollama": newOllamaEmbeddings,
+		"openai": newOpenAIEmbeddings,
+	}
+	databaseProviders = map[string]DatabaseFactory{
+		"milvus": newMilvus,
+	}
+	rerankerProviders = map[string]RerankerFactory{
+		"none": newNoReranker,
+		"llm":  newLLMReranker,
+		"http": newHTTPReranker,
+	}
+)
+
+// RegisterLLM makes an LLM provider selectable with LLM_PROVIDER=name
+func RegisterLLM(name string, factory LLMFactory) {
+	llmProviders[strings.ToLower(name)] = factory
+}
+
+// RegisterEmbeddings makes an embeddings provider selectable with EMBEDDING_PROVIDER=name
+func RegisterEmbeddings(name string, factory EmbeddingsFactory) {
+	embeddingsProviders[strings.ToLower(name)] = factory
+}
+
+// RegisterDatabase makes a vector store selectable with VECTOR_STORE=name
+func RegisterDatabase(name string, factory DatabaseFactory) {
+	databaseProviders[strings.ToLower(name)] = factory
+}
+
+// RegisterReranker makes a reranker selectable with RERANKER=name
+func RegisterReranker(name string, factory RerankerFactory) {
+	rerankerProviders[strings.ToLower(name)] = factory
+}
+
+// NewLLM builds the LLMService selected by LLM_PROVIDER
+func NewLLM(cfg config.Config) (llm.LLMService, error) {
+	factory, ok := llmProviders[strings.ToLower(cfg.LLMProvider)]
+	if !ok {
+		return nil, unknownProvider("LLM_PROVIDER", cfg.LLMProvider, keys(llmProviders))
+	}
+	return factory(cfg)
+}
+
+// NewEmbeddings builds the EmbeddingsService selected by EMBEDDING_PROVIDER
+func NewEmbeddings(cfg config.Config) (embeddings.EmbeddingsService, error) {
+	factory, ok := embeddingsProviders[strings.ToLower(cfg.EmbeddingProvider)]
+	if !ok {
+		return nil, unknownProvider("EMBEDDING_PROVIDER", cfg.EmbeddingProvider, keys(embeddingsProviders))
+	}
+	return factory(cfg)
+}
+
+// NewDatabase builds the Database selected by VECTOR_STORE for vectors of the given dimension
+func NewDatabase(ctx context.Context, cfg config.Config, dim int) (database.Database, error) {
+	factory, ok := databaseProviders[strings.ToLower(cfg.VectorStore)]
+	if !ok {
+		return nil, unknownProvider("VECTOR_STORE", cfg.VectorStore, keys(databaseProviders))
+	}
+	return factory(ctx, cfg, dim)
+}
+
+// NewReranker builds the Reranker selected by RERANKER, nil when reranking is disabled
+func NewReranker(cfg config.Config, service llm.LLMService) (rerank.Reranker, error) {
+	name := strings.ToLower(cfg.Reranker)
+	if name == "" {
+		name = "none"
+	}
+	factory, ok := rerankerProviders[name]
+	if !ok {
+		return nil, unknownProvider("RERANKER", cfg.Reranker, keys(rerankerProviders))
+	}
+	return factory(cfg, service)
+}
+
+func newOllamaLLM(cfg config.Config) (llm.LLMService, error) {
+	if err := require("LLM_PROVIDER=ollama", map[string]string{
+		"OLLAMA_ENDPOINT": cfg.OllamaEndpoint,
+		"OLLAMA_MODEL":    cfg.OllamaModel,
+	}); err != nil {
+		return nil, err
+	}
+	return llm.NewOllama(cfg.OllamaEndpoint, cfg.OllamaModel), nil
+}
+
+func newOpenAILLM(cfg config.Config) (llm.LLMService, error) {
+	settings := map[string]string{
+		"OPENAI_ENDPOINT": cfg.OpenAIEndpoint,
+		"OPENAI_MODEL":    cfg.OpenAIModel,
+	}
+	// compatible servers (vLLM, LM Studio, ...) usually run without a key
+	if isOpenAIHost(cfg.OpenAIEndpoint) {
+		settings["OPENAI_API_KEY"] = cfg.OpenAIAPIKey
+	}
+	if err := require("LLM_PROVIDER=openai", settings); err != nil {
+		return nil, err
+	}
+	return llm.NewOpenAI(cfg.OpenAIAPIKey, cfg.OpenAIEndpoint, cfg.OpenAIModel), nil
+}
+
+func newOllamaEmbeddings(cfg config.Config) (embeddings.EmbeddingsService, error) {
+	if err := require("EMBEDDING_PROVIDER=ollama", map[string]string{
+		"OLLAMA_EMBEDDING_ENDPOINT": cfg.OllamaEmbeddingEndpoint,
+		"OLLAMA_EMBEDDING_MODEL":    cfg.OllamaEmbeddingModel,
+	}); err != nil {
+		return nil, err
+	}
+
+	service := embeddings.NewOllamaEmbeddings(cfg.OllamaEmbeddingEndpoint, cfg.OllamaEmbeddingModel)
+	if cfg.OllamaEmbeddingBatch > 0 {
+		service.BatchSize = cfg.OllamaEmbeddingBatch
+	}
+	return service, nil
+}
+
+func newOpenAIEmbeddings(cfg config.Config) (embeddings.EmbeddingsService, error) {
+	settings := map[string]string{
+		"OPENAI_EMBEDDING_ENDPOINT": cfg.OpenAIEmbeddingEndpoint,
+		"OPENAI_EMBEDDING_MODEL":    cfg.OpenAIEmbeddingModel,
+	}
+	if isOpenAIHost(cfg.OpenAIEmbeddingEndpoint) {
+		settings["OPENAI_EMBEDDING_API_KEY"] = cfg.OpenAIEmbeddingAPIKey
+	}
+	if err := require("EMBEDDING_PROVIDER=openai", settings); err != nil {
+		return nil, err
+	}
+	if cfg.OpenAIEmbeddingDims < 0 {
+		return nil, fmt.Errorf("OPENAI_EMBEDDING_DIMENSIONS must not be negative, got %d", cfg.OpenAIEmbeddingDims)
+	}
+
+	service := embeddings.NewOpenAIEmbeddings(cfg.OpenAIEmbeddingAPIKey, cfg.OpenAIEmbeddingEndpoint, cfg.OpenAIEmbeddingModel)
+	service.Dimensions = cfg.OpenAIEmbeddingDims
+	if cfg.OpenAIEmbeddingBatch > 0 {
+		service.BatchSize = cfg.OpenAIEmbeddingBatch
+	}
+	return service, nil
+}
+
+func newMilvus(ctx context.Context, cfg config.Config, dim int) (database.Database, error) {
+	if err := require("VECTOR_STORE=milvus", map[string]string{
+		"MILVUS_HOST": cfg.MilvusHost,
+	}); err != nil {
+		return nil, err
+	}
+	return database.NewMilvus(ctx, cfg.MilvusHost, dim, cfg.MilvusDocumentsMetric, cfg.MilvusChunksMetric)
+}
+
+func newNoReranker(cfg config.Config, service llm.LLMService) (rerank.Reranker, error) {
+	return nil, nil
+}
+
+func newLLMReranker(cfg config.Config, service llm.LLMService) (rerank.Reranker, error) {
+	return rerank.NewLLMReranker(service), nil
+}
+
+func newHTTPReranker(cfg config.Config, service llm.LLMService) (rerank.Reranker, error) {
+	if err := require("RERANKER=http", map[string]string{
+		"RERANK_ENDPOINT": cfg.RerankEndpoint,
+	}); err != nil {
+		return nil, err
+	}
+	return rerank.NewHTTPReranker(cfg.RerankEndpoint, cfg.RerankAPIKey, cfg.RerankModel), nil
+}
+
+// require returns an error listing the settings left empty
+func require(provider string, settings map[string]string) error {
+	var missing []string
+	for name, value := range settings {
+		if strings.TrimSpace(value) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("%s requires %s to be set", provider, strings.Join(missing, ", "))
+}
+
+// isOpenAIHost reports whether the endpoint is the official OpenAI API, which needs an API key
+func isOpenAIHost(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	return err == nil && strings.HasSuffix(u.Hostname(), "openai.com")
+}
+
+func unknownProvider(setting string, value string, available []string) error {
+	return fmt.Errorf("unknown %s %q, available: %s", setting, value, strings.Join(available, ", "))
+}
+
+func keys[T any](providers map[string]T) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
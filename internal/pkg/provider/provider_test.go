@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/config"
+)
+
+func TestNewLLM(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{
+			name: "ollama",
+			cfg:  config.Config{LLMProvider: "ollama", OllamaEndpoint: "http://localhost:11434/api/chat", OllamaModel: "llama3.2:3b"},
+		},
+		{
+			name: "openai compatible server without key",
+			cfg:  config.Config{LLMProvider: "OpenAI", OpenAIEndpoint: "http://localhost:8000/v1", OpenAIModel: "qwen"},
+		},
+		{
+			name:    "openai without key",
+			cfg:     config.Config{LLMProvider: "openai", OpenAIEndpoint: "https://api.openai.com/v1"},
+			wantErr: "LLM_PROVIDER=openai requires OPENAI_API_KEY, OPENAI_MODEL to be set",
+		},
+		{
+			name:    "unknown provider",
+			cfg:     config.Config{LLMProvider: "claude"},
+			wantErr: `unknown LLM_PROVIDER "claude", available: ollama, openai`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := NewLLM(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewLLM() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || service == nil {
+				t.Errorf("NewLLM() = %v, %v, want a service", service, err)
+			}
+		})
+	}
+}
+
+func TestNewReranker(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantNil bool
+		wantErr string
+	}{
+		{name: "disabled by default", cfg: config.Config{}, wantNil: true},
+		{name: "none", cfg: config.Config{Reranker: "none"}, wantNil: true},
+		{name: "llm", cfg: config.Config{Reranker: "llm"}},
+		{name: "http", cfg: config.Config{Reranker: "http", RerankEndpoint: "http://localhost:8080"}},
+		{name: "http without endpoint", cfg: config.Config{Reranker: "http"}, wantErr: "RERANKER=http requires RERANK_ENDPOINT to be set"},
+		{name: "unknown", cfg: config.Config{Reranker: "cohere"}, wantErr: `unknown RERANKER "cohere", available: http, llm, none`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reranker, err := NewReranker(tt.cfg, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("NewReranker() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || (reranker == nil) != tt.wantNil {
+				t.Errorf("NewReranker() = %v, %v, want nil %v", reranker, err, tt.wantNil)
+			}
+		})
+	}
+}
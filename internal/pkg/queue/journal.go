@@ -0,0 +1,305 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/fsutil"
+)
+
+// Job is a unit of ingestion work: the documents of one upload task
+type Job struct {
+	ID         string            `json:"id"` // Task ID the job belongs to
+	Docs       []models.Document `json:"docs"`
+	EnqueuedAt time.Time         `json:"enqueued_at"`
+}
+
+const (
+	opEnqueue = "enqueue"
+	opDone    = "done"
+)
+
+// DefaultCompactAfter is the number of done records appended before the journal is rewritten
+// with the pending jobs only
+const DefaultCompactAfter = 100
+
+// MaxRecordSize is the size of the largest journal record, a larger job is refused
+const MaxRecordSize = 64 << 20
+
+// ErrRecordTooLarge is returned when appending a job larger than MaxRecordSize
+var ErrRecordTooLarge = errors.New("job is too large to be journaled")
+
+// record is a single line of the journal file
+type record struct {
+	Op  string `json:"op"`
+	ID  string `json:"id"`
+	Job *Job   `json:"job,omitempty"`
+}
+
+// Journal is an append-only file of enqueued and completed jobs, so that
+// accepted work survives a restart
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	pending map[string]Job
+	order   []string
+
+	CompactAfter int // Done records appended before the journal is compacted
+	done         int // Done records appended since the last compaction
+}
+
+// OpenJournal opens (or creates) the journal at path and replays it.
+// An empty path keeps the journal in memory only.
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{
+		path:         path,
+		pending:      make(map[string]Job),
+		CompactAfter: DefaultCompactAfter,
+	}
+
+	if path == "" {
+		return j, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	if err := j.replay(); err != nil {
+		return nil, err
+	}
+
+	// rewrite the journal with the pending jobs only, so it doesn't grow forever
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// replay reads the journal file and rebuilds the pending jobs
+func (j *Journal) replay() error {
+	file, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := readLine(reader, MaxRecordSize)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, ErrRecordTooLarge) {
+			log.Printf("Skipping journal record larger than %d bytes", MaxRecordSize)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read journal: %w", err)
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			// a crash can leave a truncated last line
+			log.Printf("Skipping corrupted journal record: %v", err)
+			continue
+		}
+
+		switch rec.Op {
+		case opEnqueue:
+			if rec.Job != nil {
+				j.add(*rec.Job)
+			}
+		case opDone:
+			j.remove(rec.ID)
+		}
+	}
+
+	return nil
+}
+
+// readLine returns the next line of r without its newline. A line longer than max is
+// skipped without being kept in memory and ErrRecordTooLarge is returned.
+func readLine(r *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	size := 0
+	for {
+		chunk, err := r.ReadSlice('\n')
+		size += len(chunk)
+		if size <= max+1 {
+			line = append(line, chunk...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		// the last line may have no newline
+		if errors.Is(err, io.EOF) && size > 0 {
+			err = nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if size > max+1 || len(line) > max {
+			return nil, ErrRecordTooLarge
+		}
+		return line, nil
+	}
+}
+
+// compact rewrites the journal with the pending jobs only and reopens it for appending. The
+// previous file stays in use until the new one replaces it.
+func (j *Journal) compact() error {
+	var buf bytes.Buffer
+	for _, id := range j.order {
+		job := j.pending[id]
+		if err := writeRecord(&buf, record{Op: opEnqueue, ID: id, Job: &job}); err != nil {
+			return err
+		}
+	}
+
+	if err := fsutil.WriteFileAtomic(j.path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to replace journal: %w", err)
+	}
+
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file = file
+	j.done = 0
+	return nil
+}
+
+// Append durably records a new job
+func (j *Journal) Append(job Job) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		data, err := marshalRecord(record{Op: opEnqueue, ID: job.ID, Job: &job})
+		if err != nil {
+			return err
+		}
+		// a larger record couldn't be replayed
+		if len(data) > MaxRecordSize {
+			return fmt.Errorf("%w: %d bytes, at most %d", ErrRecordTooLarge, len(data), MaxRecordSize)
+		}
+		if _, err := j.file.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to write journal record: %w", err)
+		}
+		if err := j.file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync journal: %w", err)
+		}
+	}
+
+	j.add(job)
+	return nil
+}
+
+// Done records that a job has been processed and must not be replayed
+func (j *Journal) Done(id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file != nil {
+		if err := writeRecord(j.file, record{Op: opDone, ID: id}); err != nil {
+			return err
+		}
+		j.done++
+	}
+
+	j.remove(id)
+
+	// the completed jobs keep their records until the journal is compacted
+	if j.file != nil && j.CompactAfter > 0 && j.done >= j.CompactAfter {
+		if err := j.compact(); err != nil {
+			log.Printf("Failed to compact journal: %v", err)
+		}
+	}
+	return nil
+}
+
+// Pending returns the jobs not yet marked as done, in enqueue order
+func (j *Journal) Pending() []Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jobs := make([]Job, 0, len(j.order))
+	for _, id := range j.order {
+		jobs = append(jobs, j.pending[id])
+	}
+	return jobs
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+func (j *Journal) add(job Job) {
+	if _, exists := j.pending[job.ID]; !exists {
+		j.order = append(j.order, job.ID)
+	}
+	j.pending[job.ID] = job
+}
+
+func (j *Journal) remove(id string) {
+	if _, exists := j.pending[id]; !exists {
+		return
+	}
+	delete(j.pending, id)
+	for i, pendingID := range j.order {
+		if pendingID == id {
+			j.order = append(j.order[:i], j.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func marshalRecord(rec record) ([]byte, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal journal record: %w", err)
+	}
+	return data, nil
+}
+
+func writeRecord(w io.Writer, rec record) error {
+	data, err := marshalRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal record: %w", err)
+	}
+	return nil
+}
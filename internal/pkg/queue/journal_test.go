@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestJournalReplaysPendingJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingest.journal")
+
+	journal, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+
+	for _, id := range []string{"first", "second", "third"} {
+		job := Job{ID: id, Docs: []models.Document{{Filename: id + ".txt", Content: "content"}}}
+		if err := journal.Append(job); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if err := journal.Done("second"); err != nil {
+		t.Fatalf("Done() error = %v", err)
+	}
+	journal.Close()
+
+	reopened, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	pending := reopened.Pending()
+	if len(pending) != 2 || pending[0].ID != "first" || pending[1].ID != "third" {
+		t.Fatalf("Pending() = %v, want [first third]", pending)
+	}
+	if pending[1].Docs[0].Filename != "third.txt" {
+		t.Errorf("replayed job filename = %q, want %q", pending[1].Docs[0].Filename, "third.txt")
+	}
+}
+
+func TestReadLineSkipsLongLines(t *testing.T) {
+	// the reader buffer is smaller than the long line, which is read in several parts
+	input := "first\n" + strings.Repeat("x", 40) + "\nthird"
+	reader := bufio.NewReaderSize(strings.NewReader(input), 16)
+
+	var got []string
+	for {
+		line, err := readLine(reader, 10)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, ErrRecordTooLarge) {
+			got = append(got, "too large")
+			continue
+		}
+		if err != nil {
+			t.Fatalf("readLine() error = %v", err)
+		}
+		got = append(got, string(line))
+	}
+
+	if want := []string{"first", "too large", "third"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("readLine() = %q, want %q", got, want)
+	}
+}
+
+func TestJournalCompactsDoneJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingest.journal")
+
+	journal, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+	defer journal.Close()
+	journal.CompactAfter = 2
+
+	for _, id := range []string{"first", "second", "third"} {
+		if err := journal.Append(Job{ID: id}); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	for _, id := range []string{"first", "second"} {
+		if err := journal.Done(id); err != nil {
+			t.Fatalf("Done() error = %v", err)
+		}
+	}
+
+	// the second done record rewrote the journal with the third job only
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 || !strings.Contains(string(data), `"third"`) {
+		t.Errorf("journal = %q, want the third job only", data)
+	}
+
+	// records appended after the compaction go to the new file
+	if err := journal.Append(Job{ID: "fourth"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	journal.Close()
+
+	reopened, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("OpenJournal() reopen error = %v", err)
+	}
+	defer reopened.Close()
+	if pending := reopened.Pending(); len(pending) != 2 || pending[0].ID != "third" || pending[1].ID != "fourth" {
+		t.Errorf("Pending() = %v, want [third fourth]", pending)
+	}
+}
+
+func TestPoolProcessesJobs(t *testing.T) {
+	journal, _ := OpenJournal("")
+
+	done := make(chan string, 3)
+	pool := NewPool(2, journal, func(ctx context.Context, job Job) {
+		done <- job.ID
+	})
+	pool.Start()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := pool.Enqueue(Job{ID: id}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		seen[<-done] = true
+	}
+	pool.Stop()
+
+	if len(seen) != 3 {
+		t.Errorf("processed jobs = %v, want a, b and c", seen)
+	}
+	if err := pool.Enqueue(Job{ID: "late"}); err != ErrStopped {
+		t.Errorf("Enqueue() after Stop error = %v, want ErrStopped", err)
+	}
+}
+
+func TestPoolStopCancelsRunningJobs(t *testing.T) {
+	journal, _ := OpenJournal("")
+
+	started := make(chan struct{})
+	pool := NewPool(1, journal, func(ctx context.Context, job Job) {
+		close(started)
+		<-ctx.Done()
+	})
+	pool.Start()
+
+	if err := pool.Enqueue(Job{ID: "slow"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	<-started
+	pool.Stop()
+
+	// the cancelled job stays pending so it is resumed on the next start
+	if pending := journal.Pending(); len(pending) != 1 || pending[0].ID != "slow" {
+		t.Errorf("Pending() = %v, want [slow]", pending)
+	}
+}
@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrStopped is returned when enqueuing on a stopped pool
+var ErrStopped = errors.New("queue is stopped")
+
+// Handler processes a single job. ctx is cancelled when the pool stops.
+type Handler func(ctx context.Context, job Job)
+
+// Pool runs journaled jobs on a fixed number of workers
+type Pool struct {
+	workers int
+	journal *Journal
+	handler Handler
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []Job
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewPool creates a pool of workers processing the jobs of the journal with handler
+func NewPool(workers int, journal *Journal, handler Handler) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &Pool{
+		workers: workers,
+		journal: journal,
+		handler: handler,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+
+	return p
+}
+
+// Start queues the jobs left pending in the journal and starts the workers
+func (p *Pool) Start() {
+	p.mu.Lock()
+	p.pending = append(p.pending, p.journal.Pending()...)
+	if len(p.pending) > 0 {
+		log.Printf("Resuming %d pending ingestion jobs", len(p.pending))
+	}
+	p.mu.Unlock()
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+// Enqueue journals the job and hands it to the workers
+func (p *Pool) Enqueue(job Job) error {
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now().UTC()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped {
+		return ErrStopped
+	}
+
+	if err := p.journal.Append(job); err != nil {
+		return err
+	}
+
+	p.pending = append(p.pending, job)
+	p.cond.Signal()
+
+	return nil
+}
+
+// Len returns the number of jobs waiting for a worker
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending)
+}
+
+// Stop cancels the running jobs and waits for the workers to return. Cancelled and
+// waiting jobs stay in the journal and are resumed on the next start.
+func (p *Pool) Stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	p.cancel()
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		for len(p.pending) == 0 && !p.stopped {
+			p.cond.Wait()
+		}
+		if p.stopped {
+			p.mu.Unlock()
+			return
+		}
+		job := p.pending[0]
+		p.pending = p.pending[1:]
+		p.mu.Unlock()
+
+		p.handler(p.ctx, job)
+
+		// a job interrupted by Stop is not done, it is resumed on the next start
+		if p.ctx.Err() != nil {
+			return
+		}
+
+		if err := p.journal.Done(job.ID); err != nil {
+			log.Printf("Job %s: failed to mark as done in journal: %v", job.ID, err)
+		}
+	}
+}
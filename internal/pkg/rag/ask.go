@@ -0,0 +1,244 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/llm"
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// RetrieveOptions controls which chunks are retrieved for a question
+type RetrieveOptions struct {
+	Filter        models.Filter // Restricts the retrieval to the matching documents
+	KeywordWeight *float64      // Share of the keyword search in the fusion, nil uses Rag.KeywordWeight
+	MinScore      *float32      // Minimum vector similarity of a chunk, nil uses Rag.MinScore
+}
+
+// Retrieve returns the chunks most relevant to the question, best first. The vector search is
+// fused with the keyword search when a keyword index is configured and the keyword weight is set.
+// With a minimum score, chunks less similar to the question are dropped and nothing is returned
+// when no chunk of the vector search reaches it, even if the keyword search matched.
+func (r *Rag) Retrieve(ctx context.Context, question string, opts RetrieveOptions) ([]models.Embedding, error) {
+	weight := r.KeywordWeight
+	if opts.KeywordWeight != nil {
+		weight = *opts.KeywordWeight
+	}
+	if weight < 0 || weight > 1 {
+		return nil, fmt.Errorf("keyword weight must be between 0 and 1")
+	}
+	if r.Keywords == nil {
+		weight = 0
+	}
+
+	minScore := r.MinScore
+	if opts.MinScore != nil {
+		minScore = *opts.MinScore
+	}
+
+	// the reranker picks the best chunks among a larger set of candidates
+	candidates := DefaultTopK
+	if r.Reranker != nil && r.Rerank.Candidates > 0 {
+		candidates = r.Rerank.Candidates
+	}
+
+	chunks, err := r.hybridSearch(ctx, question, candidates, weight, minScore, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Reranker == nil {
+		return chunks, nil
+	}
+	return r.rerank(ctx, question, chunks)
+}
+
+// hybridSearch returns the topK chunks of the vector search, the keyword search or their fusion
+func (r *Rag) hybridSearch(ctx context.Context, question string, topK int, weight float64, minScore float32, filter models.Filter) ([]models.Embedding, error) {
+	// pure vector search keeps the similarity scores
+	if weight == 0 {
+		return r.vectorSearch(ctx, question, topK, minScore, filter)
+	}
+
+	// each search brings more candidates than needed, the fusion promotes the ones both agree on
+	keyword, err := r.keywordSearch(ctx, question, 2*topK, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var vector []models.Embedding
+	if weight < 1 {
+		vector, err = r.vectorSearch(ctx, question, 2*topK, minScore, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		// keyword matches alone don't make the question relevant
+		if minScore > 0 && len(vector) == 0 {
+			return nil, nil
+		}
+	}
+
+	fused := Fuse(vector, keyword, weight)
+	if len(fused) > topK {
+		fused = fused[:topK]
+	}
+	return fused, nil
+}
+
+// keywordPages is the number of pages of topK keyword matches checked against the filter
+// before giving up on finding topK matching chunks
+const keywordPages = 5
+
+// keywordSearch returns the topK chunks matching the filter with the best BM25 score for the
+// text, best first, their Score is the BM25 score. The keyword index only knows the validity
+// of the chunks: the database checks the rest of the filter and returns the chunks.
+func (r *Rag) keywordSearch(ctx context.Context, text string, topK int, filter models.Filter) ([]models.Embedding, error) {
+	hits := r.Keywords.Search(text, keywordPages*topK, filter.AsOf)
+
+	var chunks []models.Embedding
+	for start := 0; start < len(hits) && len(chunks) < topK; start += topK {
+		page := hits[start:min(start+topK, len(hits))]
+		ids := make([]string, len(page))
+		for i, hit := range page {
+			ids[i] = hit.ChunkID
+		}
+
+		found, err := r.Database.GetChunksByID(ctx, ids, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keyword matches: %w", err)
+		}
+		byID := make(map[string]models.Embedding, len(found))
+		for _, chunk := range found {
+			byID[chunk.ID] = chunk
+		}
+
+		for _, hit := range page {
+			if chunk, ok := byID[hit.ChunkID]; ok {
+				chunk.Score = float32(hit.Score)
+				chunks = append(chunks, chunk)
+			}
+		}
+	}
+
+	if len(chunks) > topK {
+		chunks = chunks[:topK]
+	}
+	return chunks, nil
+}
+
+// vectorSearch returns the topK chunks most similar to the text, dropping the ones scoring below minScore
+func (r *Rag) vectorSearch(ctx context.Context, text string, topK int, minScore float32, filter models.Filter) ([]models.Embedding, error) {
+	vector, err := r.Embeddings.Vectorize(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := r.Database.Search(ctx, vector, topK, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return aboveScore(chunks, minScore), nil
+}
+
+// aboveScore returns the chunks scoring at least minScore, keeping their order
+func aboveScore(chunks []models.Embedding, minScore float32) []models.Embedding {
+	if minScore <= 0 {
+		return chunks
+	}
+
+	kept := chunks[:0:0]
+	for _, chunk := range chunks {
+		if chunk.Score >= minScore {
+			kept = append(kept, chunk)
+		}
+	}
+	return kept
+}
+
+// Sources loads the documents of the retrieved chunks and packs the chunks into the prompt context
+func (r *Rag) Sources(ctx context.Context, chunks []models.Embedding) []Source {
+	return BuildContext(chunks, r.documents(ctx, chunks), r.Context)
+}
+
+// Prompt builds the prompt answering the question from the numbered sources
+func Prompt(question string, sources []Source) string {
+	return fmt.Sprintf("Given the following numbered sources:\n\n%s\n\n"+
+		"Answer the question using only these sources. After each statement, cite the sources supporting it "+
+		"with their number in square brackets, for example [1] or [2, 3].\n\nQuestion: %s", FormatContext(sources), question)
+}
+
+// Answer is the response to a question
+type Answer struct {
+	Text      string     // Answer with [n] citation markers
+	Sources   []Source   // Chunks given to the LLM
+	Citations []Citation // Sources cited by the answer
+	Timing    Timing
+}
+
+// Timing reports how long each stage of answering took
+type Timing struct {
+	Retrieval  time.Duration
+	Generation time.Duration
+}
+
+// Ask answers the question from the chunks retrieved for it.
+// It returns an empty answer without sources when no relevant chunk was found.
+func (r *Rag) Ask(ctx context.Context, question string, opts RetrieveOptions) (Answer, error) {
+	return r.AskStream(ctx, question, opts, nil, nil)
+}
+
+// AskStream answers the question like Ask, calling onSources once the chunks given to the
+// LLM are known and onDelta with each piece of generated text. LLMs that can't stream
+// deliver the whole answer as a single delta. Both callbacks are optional.
+func (r *Rag) AskStream(ctx context.Context, question string, opts RetrieveOptions, onSources func(sources []Source) error, onDelta func(delta string) error) (Answer, error) {
+	start := time.Now()
+
+	chunks, err := r.Retrieve(ctx, question, opts)
+	if err != nil {
+		return Answer{}, err
+	}
+
+	sources := r.Sources(ctx, chunks)
+	timing := Timing{Retrieval: time.Since(start)}
+
+	if onSources != nil {
+		if err := onSources(sources); err != nil {
+			return Answer{}, err
+		}
+	}
+
+	if len(sources) == 0 {
+		return Answer{Timing: timing}, nil
+	}
+
+	start = time.Now()
+	generated, err := r.generate(ctx, Prompt(question, sources), onDelta)
+	if err != nil {
+		return Answer{}, err
+	}
+	timing.Generation = time.Since(start)
+
+	text, citations := Citations(generated, sources)
+
+	return Answer{Text: text, Sources: sources, Citations: citations, Timing: timing}, nil
+}
+
+// generate streams the completion to onDelta when both the caller and the LLM support it
+func (r *Rag) generate(ctx context.Context, prompt string, onDelta func(delta string) error) (string, error) {
+	if onDelta == nil {
+		return r.LLM.Generate(ctx, prompt)
+	}
+
+	if streaming, ok := r.LLM.(llm.StreamingLLMService); ok {
+		return streaming.GenerateStream(ctx, prompt, onDelta)
+	}
+
+	generated, err := r.LLM.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return generated, onDelta(generated)
+}
@@ -0,0 +1,36 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestAskMinScore(t *testing.T) {
+	db := &fakeDatabase{
+		chunks: []models.Embedding{
+			{ID: "c1", DocumentID: "d1", TextChunk: "Unrelated text.", Score: 0.3},
+		},
+	}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+	r.MinScore = 0.5
+
+	// no chunk is similar enough: no sources and no LLM call (the LLM is nil)
+	answer, err := r.Ask(context.Background(), "question", RetrieveOptions{})
+	if err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if len(answer.Sources) != 0 {
+		t.Errorf("Ask() sources = %v, want none", answer.Sources)
+	}
+
+	lower := float32(0.2)
+	chunks, err := r.Retrieve(context.Background(), "question", RetrieveOptions{MinScore: &lower})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Errorf("Retrieve() with min_score 0.2 = %v, want c1", chunks)
+	}
+}
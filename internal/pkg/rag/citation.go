@@ -0,0 +1,165 @@
+package rag
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jonathanhecl/chunker"
+)
+
+// maxQuoteChars bounds the quote of a citation when no sentence of the chunk can be matched
+const maxQuoteChars = 300
+
+// citationMarker matches [1], [2, 3] and [2,3] markers in an answer
+var citationMarker = regexp.MustCompile(`\s*\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Citation links a numbered marker of the answer to the chunk supporting it
+type Citation struct {
+	Index      int     `json:"index"`       // Number used in the answer markers
+	ChunkID    string  `json:"chunk_id"`    // ID of the chunk
+	DocumentID string  `json:"document_id"` // ID of the document the chunk belongs to
+	Filename   string  `json:"filename"`
+	Link       string  `json:"link"`
+	Order      int64   `json:"order"`                 // Position of the chunk in the document
+	Page       string  `json:"page,omitempty"`        // Page of the chunk, for paged formats like PDF
+	Heading    string  `json:"heading,omitempty"`     // Heading path of the chunk, e.g. "Scope > Exclusions"
+	Score      float32 `json:"score"`                 // Similarity of the chunk, or its rerank score
+	FusedScore float32 `json:"fused_score,omitempty"` // Rank fusion score, with hybrid retrieval
+	Quote      string  `json:"quote"`                 // Span of the chunk supporting the answer
+}
+
+// Citations resolves the [n] markers of the answer against the sources. Markers pointing
+// to unknown sources are removed from the answer. When the answer cites nothing, every
+// source is returned so the client can still show where the answer came from.
+func Citations(answer string, sources []Source) (string, []Citation) {
+	byIndex := make(map[int]Source, len(sources))
+	for _, source := range sources {
+		byIndex[source.Index] = source
+	}
+
+	// sentences of the answer citing each source, used to pick the quote
+	claims := make(map[int][]string)
+	for _, sentence := range chunker.ChunkSentences(answer) {
+		for _, index := range markerIndexes(sentence) {
+			if _, ok := byIndex[index]; ok {
+				claims[index] = append(claims[index], citationMarker.ReplaceAllString(sentence, ""))
+			}
+		}
+	}
+
+	answer = citationMarker.ReplaceAllStringFunc(answer, func(marker string) string {
+		var valid []string
+		for _, index := range markerIndexes(marker) {
+			if _, ok := byIndex[index]; ok {
+				valid = append(valid, strconv.Itoa(index))
+			}
+		}
+		if len(valid) == 0 {
+			return ""
+		}
+		// keep the spacing before the marker
+		space := marker[:strings.Index(marker, "[")]
+		return space + "[" + strings.Join(valid, ", ") + "]"
+	})
+
+	cited := make([]int, 0, len(claims))
+	for index := range claims {
+		cited = append(cited, index)
+	}
+	if len(cited) == 0 {
+		for _, source := range sources {
+			cited = append(cited, source.Index)
+		}
+	}
+	sort.Ints(cited)
+
+	citations := make([]Citation, 0, len(cited))
+	for _, index := range cited {
+		source := byIndex[index]
+		citations = append(citations, source.Citation(quote(source.Text, claims[index])))
+	}
+
+	return strings.TrimSpace(answer), citations
+}
+
+// SourceCitations describes the sources before the answer is known, quoting the start of each chunk
+func SourceCitations(sources []Source) []Citation {
+	citations := make([]Citation, len(sources))
+	for i, source := range sources {
+		citations[i] = source.Citation(truncate(source.Text, maxQuoteChars))
+	}
+	return citations
+}
+
+// Citation describes the source with the given quote
+func (s Source) Citation(quote string) Citation {
+	return Citation{
+		Index:      s.Index,
+		ChunkID:    s.Embedding.ID,
+		DocumentID: s.Embedding.DocumentID,
+		Filename:   s.Document.Filename,
+		Link:       s.Document.Link,
+		Order:      s.Embedding.Order,
+		Page:       s.Embedding.Metadata["page"],
+		Heading:    s.Embedding.Metadata["heading"],
+		Score:      s.Embedding.Score,
+		FusedScore: s.Embedding.FusedScore,
+		Quote:      quote,
+	}
+}
+
+// markerIndexes returns the source numbers of every marker in the text
+func markerIndexes(text string) []int {
+	var indexes []int
+	for _, match := range citationMarker.FindAllStringSubmatch(text, -1) {
+		for _, number := range strings.Split(match[1], ",") {
+			if index, err := strconv.Atoi(strings.TrimSpace(number)); err == nil {
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	return indexes
+}
+
+// quote returns the sentence of the chunk sharing the most words with the claims citing it
+func quote(text string, claims []string) string {
+	claimWords := make(map[string]struct{})
+	for _, claim := range claims {
+		for _, word := range words(claim) {
+			claimWords[word] = struct{}{}
+		}
+	}
+
+	best, bestScore := "", 0
+	for _, sentence := range chunker.ChunkSentences(text) {
+		score := 0
+		for _, word := range words(sentence) {
+			if _, ok := claimWords[word]; ok {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = sentence, score
+		}
+	}
+
+	if best == "" {
+		return truncate(strings.TrimSpace(text), maxQuoteChars)
+	}
+	return best
+}
+
+// words returns the lowercase words of the text longer than three characters
+func words(text string) []string {
+	var result []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127)
+	}) {
+		if len([]rune(word)) > 3 {
+			result = append(result, word)
+		}
+	}
+	return result
+}
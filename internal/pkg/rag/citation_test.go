@@ -0,0 +1,54 @@
+package rag
+
+import (
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestCitations(t *testing.T) {
+	sources := []Source{
+		{
+			Index:     1,
+			Text:      "Logs are kept for one year. Backups run every night.",
+			Embedding: models.Embedding{ID: "c1", DocumentID: "d1", Order: 2, Score: 0.9},
+			Document:  models.Document{ID: "d1", Filename: "ops.md"},
+		},
+		{
+			Index:     2,
+			Text:      "Access reviews happen every quarter.",
+			Embedding: models.Embedding{ID: "c2", DocumentID: "d2", Order: 0, Score: 0.7},
+			Document:  models.Document{ID: "d2", Link: "https://example.com/access"},
+		},
+	}
+
+	answer, citations := Citations("Backups run nightly [1]. Reviews are quarterly [2, 7]. Unknown [9].", sources)
+
+	if want := "Backups run nightly [1]. Reviews are quarterly [2]. Unknown."; answer != want {
+		t.Errorf("answer = %q, want %q", answer, want)
+	}
+	if len(citations) != 2 {
+		t.Fatalf("got %d citations, want 2", len(citations))
+	}
+	if citations[0].ChunkID != "c1" || citations[0].Filename != "ops.md" || citations[0].Order != 2 {
+		t.Errorf("first citation = %+v", citations[0])
+	}
+	if citations[0].Quote != "Backups run every night." {
+		t.Errorf("first citation quote = %q, want the backup sentence", citations[0].Quote)
+	}
+	if citations[1].Link != "https://example.com/access" || citations[1].Index != 2 {
+		t.Errorf("second citation = %+v", citations[1])
+	}
+}
+
+func TestCitationsWithoutMarkers(t *testing.T) {
+	sources := []Source{
+		{Index: 1, Text: "first", Embedding: models.Embedding{ID: "c1"}},
+		{Index: 2, Text: "second", Embedding: models.Embedding{ID: "c2"}},
+	}
+
+	_, citations := Citations("An answer without markers.", sources)
+	if len(citations) != 2 {
+		t.Errorf("got %d citations, want every source", len(citations))
+	}
+}
@@ -0,0 +1,184 @@
+package rag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+const (
+	// OrderByScore keeps the best ranked chunks first
+	OrderByScore = "score"
+	// OrderByDocument groups the chunks by document, in reading order
+	OrderByDocument = "document"
+
+	// minOverlap is the shortest shared text considered an overlap between adjacent chunks
+	minOverlap = 20
+)
+
+// ContextOptions controls how retrieved chunks are packed into the prompt
+type ContextOptions struct {
+	MaxChars int    // Budget in characters for the chunk texts, 0 means no limit
+	Order    string // OrderByScore or OrderByDocument
+}
+
+// Source is a chunk selected for the prompt, together with the document it comes from
+type Source struct {
+	Index     int              // 1-based position of the chunk in the prompt
+	Text      string           // Chunk text as sent to the LLM, without overlap with other sources
+	Embedding models.Embedding // Retrieved chunk
+	Document  models.Document  // Document the chunk belongs to (without content)
+}
+
+// BuildContext selects the best ranked chunks fitting in the budget, drops duplicated
+// and overlapping text and orders them as requested. chunks must be sorted by score.
+func BuildContext(chunks []models.Embedding, docs map[string]models.Document, opts ContextOptions) []Source {
+	var sources []Source
+	used := 0
+
+	for _, chunk := range chunks {
+		text := strings.TrimSpace(chunk.TextChunk)
+		if text == "" || isDuplicate(text, sources) {
+			continue
+		}
+
+		text = trimAdjacentOverlap(text, chunk, sources)
+		if text == "" {
+			continue
+		}
+
+		length := utf8.RuneCountInString(text)
+		if opts.MaxChars > 0 && used+length > opts.MaxChars {
+			// always answer from something: the best chunk is cut to the budget
+			if len(sources) == 0 {
+				text = truncate(text, opts.MaxChars)
+				length = opts.MaxChars
+			} else {
+				continue
+			}
+		}
+
+		used += length
+		sources = append(sources, Source{
+			Text:      text,
+			Embedding: chunk,
+			Document:  documentOf(chunk.DocumentID, docs),
+		})
+	}
+
+	if opts.Order == OrderByDocument {
+		orderByDocument(sources)
+	}
+
+	for i := range sources {
+		sources[i].Index = i + 1
+	}
+
+	return sources
+}
+
+// FormatContext renders the sources as labeled blocks for the prompt
+func FormatContext(sources []Source) string {
+	var b strings.Builder
+	for _, source := range sources {
+		fmt.Fprintf(&b, "[%d] Source: %s\n%s\n\n", source.Index, sourceLabel(source.Document), source.Text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// sourceLabel names a document by filename, falling back to its link or ID
+func sourceLabel(doc models.Document) string {
+	switch {
+	case doc.Filename != "":
+		return doc.Filename
+	case doc.Link != "":
+		return doc.Link
+	default:
+		return doc.ID
+	}
+}
+
+func documentOf(id string, docs map[string]models.Document) models.Document {
+	if doc, ok := docs[id]; ok {
+		return doc
+	}
+	return models.Document{ID: id}
+}
+
+// isDuplicate reports whether the text is already contained in a selected source
+func isDuplicate(text string, sources []Source) bool {
+	normalized := normalize(text)
+	for _, source := range sources {
+		if strings.Contains(normalize(source.Text), normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimAdjacentOverlap removes the text shared with the previous or next chunk of the same
+// document when one of them is already selected
+func trimAdjacentOverlap(text string, chunk models.Embedding, sources []Source) string {
+	for _, source := range sources {
+		if source.Embedding.DocumentID != chunk.DocumentID {
+			continue
+		}
+
+		switch source.Embedding.Order {
+		case chunk.Order - 1:
+			if n := overlap(source.Text, text); n > 0 {
+				text = strings.TrimSpace(text[n:])
+			}
+		case chunk.Order + 1:
+			if n := overlap(text, source.Text); n > 0 {
+				text = strings.TrimSpace(text[:len(text)-n])
+			}
+		}
+	}
+	return text
+}
+
+// overlap returns the length in bytes of the longest suffix of first that is a prefix of second
+func overlap(first string, second string) int {
+	longest := min(len(first), len(second))
+	for n := longest; n >= minOverlap; n-- {
+		if strings.HasSuffix(first, second[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// orderByDocument groups the sources by document, documents ranked by their best chunk,
+// and sorts the chunks of each document by their order
+func orderByDocument(sources []Source) {
+	rank := make(map[string]int)
+	for i, source := range sources {
+		if _, ok := rank[source.Embedding.DocumentID]; !ok {
+			rank[source.Embedding.DocumentID] = i
+		}
+	}
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		ri, rj := rank[sources[i].Embedding.DocumentID], rank[sources[j].Embedding.DocumentID]
+		if ri != rj {
+			return ri < rj
+		}
+		return sources[i].Embedding.Order < sources[j].Embedding.Order
+	})
+}
+
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func truncate(text string, maxChars int) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	return string(runes[:maxChars])
+}
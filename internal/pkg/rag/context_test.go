@@ -0,0 +1,83 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestBuildContext(t *testing.T) {
+	shared := "the controls are reviewed every year by the security team"
+	chunks := []models.Embedding{
+		{ID: "b1", DocumentID: "b", Order: 1, TextChunk: shared + " and reported to the board.", Score: 0.9},
+		{ID: "a0", DocumentID: "a", Order: 0, TextChunk: "Access logs are kept for one year.", Score: 0.8},
+		{ID: "b0", DocumentID: "b", Order: 0, TextChunk: "Policy scope: " + shared, Score: 0.7},
+		{ID: "dup", DocumentID: "c", Order: 3, TextChunk: "access logs are KEPT for one year.", Score: 0.6},
+		{ID: "big", DocumentID: "c", Order: 4, TextChunk: strings.Repeat("x", 500), Score: 0.5},
+	}
+	docs := map[string]models.Document{
+		"a": {ID: "a", Filename: "logging.md"},
+		"b": {ID: "b", Filename: "policy.pdf"},
+	}
+
+	tests := []struct {
+		name  string
+		opts  ContextOptions
+		want  []string
+		texts map[string]string
+	}{
+		{
+			name: "by score within budget",
+			opts: ContextOptions{MaxChars: 200, Order: OrderByScore},
+			want: []string{"b1", "a0", "b0"},
+			texts: map[string]string{
+				"b0": "Policy scope:",
+			},
+		},
+		{
+			name: "by document",
+			opts: ContextOptions{MaxChars: 200, Order: OrderByDocument},
+			want: []string{"b0", "b1", "a0"},
+		},
+		{
+			name: "best chunk cut to the budget",
+			opts: ContextOptions{MaxChars: 10, Order: OrderByScore},
+			want: []string{"b1"},
+			texts: map[string]string{
+				"b1": "the contro",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := BuildContext(chunks, docs, tt.opts)
+
+			var got []string
+			for i, source := range sources {
+				got = append(got, source.Embedding.ID)
+				if source.Index != i+1 {
+					t.Errorf("source %s index = %d, want %d", source.Embedding.ID, source.Index, i+1)
+				}
+				if want, ok := tt.texts[source.Embedding.ID]; ok && source.Text != want {
+					t.Errorf("source %s text = %q, want %q", source.Embedding.ID, source.Text, want)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("BuildContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatContext(t *testing.T) {
+	sources := []Source{
+		{Index: 1, Text: "first", Document: models.Document{ID: "a", Filename: "a.txt"}},
+		{Index: 2, Text: "second", Document: models.Document{ID: "b", Link: "https://example.com/b"}},
+	}
+
+	want := "[1] Source: a.txt\nfirst\n\n[2] Source: https://example.com/b\nsecond"
+	if got := FormatContext(sources); got != want {
+		t.Errorf("FormatContext() = %q, want %q", got, want)
+	}
+}
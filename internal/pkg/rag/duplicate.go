@@ -0,0 +1,113 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// Upload modes, what to do with a document already uploaded: one with the same external ID,
+// or with the same content when the document has no external ID
+const (
+	ModeSkip       = "skip"        // keep the existing document
+	ModeReplace    = "replace"     // replace the existing document, keeping its ID with the next version, the chunks of the replaced content are deleted
+	ModeNewVersion = "new_version" // replace the existing document, keeping its ID with the next version, the chunks of the previous versions stay searchable
+)
+
+// ValidateMode fails when mode is not an upload mode
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeSkip, ModeReplace, ModeNewVersion:
+		return nil
+	}
+	return fmt.Errorf("invalid upload mode %q, expected %q, %q or %q", mode, ModeSkip, ModeReplace, ModeNewVersion)
+}
+
+// ResolveMode returns the upload mode of a document: the given one, or the default UploadMode when empty
+func (r *Rag) ResolveMode(mode string) (string, error) {
+	if mode == "" {
+		mode = r.UploadMode
+	}
+	if err := ValidateMode(mode); err != nil {
+		return "", err
+	}
+	return mode, nil
+}
+
+// findDuplicate returns the document already uploaded like doc, the latest version when there
+// are several, nil if there is none
+func (r *Rag) findDuplicate(ctx context.Context, doc models.Document) (*models.Document, error) {
+	docs, err := r.Database.FindDocuments(ctx, doc.ExternalID, doc.ContentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *models.Document
+	for i := range docs {
+		if latest == nil || docs[i].Version > latest.Version {
+			latest = &docs[i]
+		}
+	}
+	return latest, nil
+}
+
+// planDocument decides how to ingest doc, whose ContentHash is set: an update replaces the
+// document with its ID, with the next version when the content changed, an upload is checked
+// against the documents already uploaded. It returns the plan and the document replaced or
+// already uploaded, nil if there is none.
+func (r *Rag) planDocument(ctx context.Context, doc models.Document) (uploadPlan, *models.Document, error) {
+	if doc.Mode == modeUpdate {
+		existing, err := r.Database.GetDocumentInfo(ctx, doc.ID)
+		if err != nil {
+			return uploadPlan{}, nil, err
+		}
+		version := max(existing.Version, 1)
+		if existing.ContentHash != doc.ContentHash {
+			version++
+		}
+		return uploadPlan{DocumentID: doc.ID, Version: version, History: true}, &existing, nil
+	}
+
+	mode, err := r.ResolveMode(doc.Mode)
+	if err != nil {
+		return uploadPlan{}, nil, err
+	}
+	existing, err := r.findDuplicate(ctx, doc)
+	if err != nil {
+		return uploadPlan{}, nil, err
+	}
+	return planUpload(mode, doc, existing), existing, nil
+}
+
+// uploadPlan is how a document is ingested given the document already uploaded like it
+type uploadPlan struct {
+	Skip       bool   // nothing to ingest, the existing document is kept
+	DocumentID string // ID of the document replaced, empty for a new document
+	Version    int64
+	History    bool // the replaced chunks stay searchable at past times instead of being deleted
+}
+
+// planUpload decides how to ingest doc in the given mode, existing is the document already
+// uploaded like it or nil. An unchanged content is never ingested again, a changed one is
+// always the next version, the mode only decides whether the replaced chunks are kept.
+func planUpload(mode string, doc models.Document, existing *models.Document) uploadPlan {
+	if existing == nil {
+		return uploadPlan{Version: 1}
+	}
+	if mode == ModeSkip || existing.ContentHash == doc.ContentHash {
+		return uploadPlan{Skip: true, DocumentID: existing.ID}
+	}
+
+	// documents saved before versioning have no version
+	version := max(existing.Version, 1) + 1
+	return uploadPlan{DocumentID: existing.ID, Version: version, History: mode == ModeNewVersion}
+}
+
+// contentHash returns the hex SHA-256 of the text
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,118 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestResolveMode(t *testing.T) {
+	r := NewRag(nil, fakeEmbeddings{}, &fakeDatabase{}, nil)
+
+	if mode, err := r.ResolveMode(""); err != nil || mode != ModeSkip {
+		t.Errorf("ResolveMode(\"\") = %q, %v, want %q", mode, err, ModeSkip)
+	}
+	if mode, err := r.ResolveMode(ModeNewVersion); err != nil || mode != ModeNewVersion {
+		t.Errorf("ResolveMode(%q) = %q, %v", ModeNewVersion, mode, err)
+	}
+	if _, err := r.ResolveMode("overwrite"); err == nil {
+		t.Error("ResolveMode(\"overwrite\") error = nil, want invalid mode")
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	db := &fakeDatabase{docs: map[string]models.Document{
+		"v1":    {ID: "v1", ExternalID: "policy", ContentHash: "a", Version: 1},
+		"v2":    {ID: "v2", ExternalID: "policy", ContentHash: "b", Version: 2},
+		"other": {ID: "other", ContentHash: "c", Version: 1},
+	}}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		doc  models.Document
+		want string
+	}{
+		{"latest version by external ID", models.Document{ExternalID: "policy", ContentHash: "c"}, "v2"},
+		{"same content", models.Document{ContentHash: "c"}, "other"},
+		{"external ID takes precedence over content", models.Document{ExternalID: "new", ContentHash: "c"}, ""},
+		{"new content", models.Document{ContentHash: "d"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing, err := r.findDuplicate(ctx, tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if existing != nil {
+				got = existing.ID
+			}
+			if got != tt.want {
+				t.Errorf("findDuplicate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanUpload(t *testing.T) {
+	existing := &models.Document{ID: "doc", ContentHash: "old", Version: 3}
+	changed := models.Document{ContentHash: "new"}
+	unchanged := models.Document{ContentHash: "old"}
+
+	tests := []struct {
+		name     string
+		mode     string
+		doc      models.Document
+		existing *models.Document
+		want     uploadPlan
+	}{
+		{"new document", ModeSkip, changed, nil, uploadPlan{Version: 1}},
+		{"skip", ModeSkip, changed, existing, uploadPlan{Skip: true, DocumentID: "doc"}},
+		{"replace", ModeReplace, changed, existing, uploadPlan{DocumentID: "doc", Version: 4}},
+		{"new version", ModeNewVersion, changed, existing, uploadPlan{DocumentID: "doc", Version: 4, History: true}},
+		{"unchanged replace", ModeReplace, unchanged, existing, uploadPlan{Skip: true, DocumentID: "doc"}},
+		{"unchanged new version", ModeNewVersion, unchanged, existing, uploadPlan{Skip: true, DocumentID: "doc"}},
+		{"unversioned document", ModeNewVersion, changed, &models.Document{ID: "doc"}, uploadPlan{DocumentID: "doc", Version: 2, History: true}},
+		{"unversioned document replace", ModeReplace, changed, &models.Document{ID: "doc"}, uploadPlan{DocumentID: "doc", Version: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := planUpload(tt.mode, tt.doc, tt.existing); got != tt.want {
+				t.Errorf("planUpload() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanDocumentUpdate(t *testing.T) {
+	db := &fakeDatabase{docs: map[string]models.Document{
+		"doc": {ID: "doc", ContentHash: "old", Version: 2},
+	}}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+
+	tests := []struct {
+		name string
+		hash string
+		want uploadPlan
+	}{
+		{"changed content", "new", uploadPlan{DocumentID: "doc", Version: 3, History: true}},
+		{"same content", "old", uploadPlan{DocumentID: "doc", Version: 2, History: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, existing, err := r.planDocument(context.Background(), models.Document{ID: "doc", ContentHash: tt.hash, Mode: modeUpdate})
+			if err != nil {
+				t.Fatalf("planDocument() error = %v", err)
+			}
+			if plan != tt.want {
+				t.Errorf("planDocument() = %+v, want %+v", plan, tt.want)
+			}
+			if existing == nil || existing.ID != "doc" {
+				t.Errorf("planDocument() existing = %+v, want doc", existing)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// rrfK dampens the weight of the top ranks in reciprocal rank fusion
+const rrfK = 60
+
+// Fuse merges the vector and keyword rankings with weighted reciprocal rank fusion:
+// each chunk scores (1-keywordWeight)/(rrfK+rank) in the vector ranking plus
+// keywordWeight/(rrfK+rank) in the keyword ranking. Both rankings must be sorted
+// best first. The returned chunks are sorted by their FusedScore, their Score stays the
+// similarity of the vector search, 0 for the chunks it didn't find.
+func Fuse(vector, keyword []models.Embedding, keywordWeight float64) []models.Embedding {
+	scores := make(map[string]float64)
+	chunks := make(map[string]models.Embedding)
+
+	add := func(ranking []models.Embedding, weight float64, similarity bool) {
+		for rank, chunk := range ranking {
+			scores[chunk.ID] += weight / float64(rrfK+rank+1)
+			// keep the vector search copy, it carries the similarity
+			if _, ok := chunks[chunk.ID]; !ok {
+				if !similarity {
+					chunk.Score = 0
+				}
+				chunks[chunk.ID] = chunk
+			}
+		}
+	}
+	add(vector, 1-keywordWeight, true)
+	add(keyword, keywordWeight, false)
+
+	fused := make([]models.Embedding, 0, len(chunks))
+	for id, chunk := range chunks {
+		chunk.FusedScore = float32(scores[id])
+		fused = append(fused, chunk)
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		if fused[i].FusedScore != fused[j].FusedScore {
+			return fused[i].FusedScore > fused[j].FusedScore
+		}
+		return fused[i].ID < fused[j].ID
+	})
+
+	return fused
+}
+
+// SyncKeywords rebuilds the keyword index from the chunks of the database when their number
+// differs, e.g. when the index is new while documents were already ingested. It does nothing
+// without a keyword index or when the database can't list its chunks.
+func (r *Rag) SyncKeywords(ctx context.Context) error {
+	scanner, ok := r.Database.(database.ChunkScanner)
+	if r.Keywords == nil || !ok {
+		return nil
+	}
+
+	count, err := scanner.CountChunks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count chunks: %w", err)
+	}
+	if count == r.Keywords.Len() {
+		return nil
+	}
+
+	log.Printf("Keyword index has %d chunks, database has %d: rebuilding the index", r.Keywords.Len(), count)
+	if err := r.Keywords.Reset(); err != nil {
+		return err
+	}
+	err = scanner.ScanChunks(ctx, func(chunks []models.Embedding) error {
+		return r.Keywords.Add(chunks)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rebuild keyword index: %w", err)
+	}
+	log.Printf("Keyword index rebuilt, %d chunks indexed", r.Keywords.Len())
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/bm25"
+)
+
+func TestFuse(t *testing.T) {
+	vector := []models.Embedding{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.8}, {ID: "c", Score: 0.7}}
+	keyword := []models.Embedding{{ID: "c", Score: 12}, {ID: "d", Score: 3}}
+
+	tests := []struct {
+		name   string
+		weight float64
+		first  string
+	}{
+		{"vector only", 0, "a"},
+		{"balanced", 0.5, "c"}, // the only chunk found by both searches
+		{"keyword only", 1, "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fused := Fuse(vector, keyword, tt.weight)
+			if len(fused) != 4 {
+				t.Fatalf("Fuse() returned %d chunks, want 4", len(fused))
+			}
+			if fused[0].ID != tt.first {
+				t.Errorf("Fuse() first = %s, want %s", fused[0].ID, tt.first)
+			}
+			for i := 1; i < len(fused); i++ {
+				if fused[i].FusedScore > fused[i-1].FusedScore {
+					t.Errorf("Fuse() not sorted by fused score: %v", fused)
+				}
+			}
+
+			// the similarity of the vector search is kept, the BM25 score is not one
+			for _, chunk := range fused {
+				want := map[string]float32{"a": 0.9, "b": 0.8, "c": 0.7, "d": 0}[chunk.ID]
+				if chunk.Score != want {
+					t.Errorf("Fuse() %s score = %v, want %v", chunk.ID, chunk.Score, want)
+				}
+			}
+		})
+	}
+}
+
+func TestKeywordSearch(t *testing.T) {
+	chunks := []models.Embedding{
+		{ID: "c1", DocumentID: "d1", TextChunk: "Event logging of the servers.", Category: "iso", Metadata: map[string]string{"tenant": "acme"}},
+		{ID: "c2", DocumentID: "d2", TextChunk: "Event logging.", Category: "dev"},
+		{ID: "c3", DocumentID: "d3", TextChunk: "Event logging of the application.", Category: "dev"},
+	}
+	keywords, err := bm25.NewIndex("")
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+	if err := keywords.Add(chunks); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	r := NewRag(nil, fakeEmbeddings{}, &fakeDatabase{chunks: chunks}, nil)
+	r.Keywords = keywords
+
+	// c2 ranks first: c1 is only found when the next pages are checked against the filter
+	found, err := r.keywordSearch(context.Background(), "event logging", 1, models.Filter{Categories: []string{"iso"}})
+	if err != nil {
+		t.Fatalf("keywordSearch() error = %v", err)
+	}
+	if len(found) != 1 || found[0].ID != "c1" || found[0].Metadata["tenant"] != "acme" || found[0].Score <= 0 {
+		t.Errorf("keywordSearch() = %+v, want c1 with its fields and BM25 score", found)
+	}
+}
+
+func TestSyncKeywords(t *testing.T) {
+	chunks := []models.Embedding{
+		{ID: "c1", DocumentID: "d1", TextChunk: "Event logging of the servers."},
+		{ID: "c2", DocumentID: "d1", TextChunk: "Retention of the logs.", ValidTo: 100}, // a previous version
+		{ID: "c3", DocumentID: "d2", TextChunk: "Access control."},
+	}
+	keywords, err := bm25.NewIndex("")
+	if err != nil {
+		t.Fatalf("NewIndex() error = %v", err)
+	}
+	// a chunk left from a document deleted while the index was not in use
+	if err := keywords.Add([]models.Embedding{{ID: "gone", DocumentID: "d9", TextChunk: "Event logging."}}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	r := NewRag(nil, fakeEmbeddings{}, &fakeDatabase{chunks: chunks}, nil)
+	r.Keywords = keywords
+	if err := r.SyncKeywords(context.Background()); err != nil {
+		t.Fatalf("SyncKeywords() error = %v", err)
+	}
+
+	if keywords.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", keywords.Len())
+	}
+	if hits := keywords.Search("event logging", 10, nil); len(hits) != 1 || hits[0].ChunkID != "c1" {
+		t.Errorf("Search() = %v, want c1 only", hits)
+	}
+	asOf := time.Unix(50, 0)
+	if hits := keywords.Search("retention", 10, &asOf); len(hits) != 1 || hits[0].ChunkID != "c2" {
+		t.Errorf("Search() of a previous version = %v, want c2", hits)
+	}
+}
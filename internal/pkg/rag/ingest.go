@@ -0,0 +1,587 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/extract"
+	"github.com/elchemista/easy_rag/internal/pkg/history"
+	"github.com/elchemista/easy_rag/internal/pkg/queue"
+	"github.com/elchemista/easy_rag/internal/pkg/task"
+	"github.com/elchemista/easy_rag/internal/pkg/textprocessor"
+	"github.com/google/uuid"
+)
+
+// StartWorkers starts the ingestion worker pool backed by the given journal.
+// Jobs left in the journal by a previous run are resumed; tasks that were
+// interrupted without a journaled job are marked as failed.
+func (r *Rag) StartWorkers(journal *queue.Journal, workers int) {
+	pending := make(map[string]struct{})
+	for _, job := range journal.Pending() {
+		pending[job.ID] = struct{}{}
+		trackTask(r.Tasks.Requeue(job.ID))
+	}
+
+	for _, id := range r.Tasks.Unfinished() {
+		if _, ok := pending[id]; !ok {
+			trackTask(r.Tasks.Fail(id, fmt.Errorf("interrupted by server restart")))
+		}
+	}
+
+	r.queue = queue.NewPool(workers, journal, r.processJob)
+	r.queue.Start()
+}
+
+// StopWorkers cancels the running ingestion jobs and waits for the workers to return.
+// Interrupted jobs are resumed by the next StartWorkers.
+func (r *Rag) StopWorkers() {
+	if r.queue != nil {
+		r.queue.Stop()
+	}
+}
+
+// Enqueue creates a task for the documents and queues them for ingestion
+func (r *Rag) Enqueue(docs []models.Document) (task.Task, error) {
+	if r.queue == nil {
+		return task.Task{}, fmt.Errorf("ingestion workers are not started")
+	}
+
+	taskID := uuid.NewString()
+
+	filenames := make([]string, len(docs))
+	for idx, doc := range docs {
+		filenames[idx] = doc.Filename
+	}
+
+	info, err := r.Tasks.Create(taskID, filenames)
+	if err != nil {
+		return task.Task{}, err
+	}
+
+	// the journal keeps the text of extracted files once, in their sections, the worker
+	// rebuilds their content
+	journaled := make([]models.Document, len(docs))
+	for idx, doc := range docs {
+		if len(doc.Sections) > 0 {
+			doc.Content = ""
+		}
+		journaled[idx] = doc
+	}
+
+	if err := r.queue.Enqueue(queue.Job{ID: taskID, Docs: journaled}); err != nil {
+		trackTask(r.Tasks.Fail(taskID, err))
+		return task.Task{}, fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	return info, nil
+}
+
+// processJob ingests the documents of a job, one after the other
+func (r *Rag) processJob(ctx context.Context, job queue.Job) {
+	taskID := job.ID
+	log.Printf("Task %s: started processing", taskID)
+	defer log.Printf("Task %s: completed processing", taskID)
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("Task %s: panic during processing: %v", taskID, rec)
+			trackTask(r.Tasks.Fail(taskID, fmt.Errorf("panic during processing: %v", rec)))
+		}
+	}()
+
+	info, err := r.Tasks.Get(taskID)
+	if err != nil {
+		log.Printf("Task %s: %v", taskID, err)
+		return
+	}
+
+	trackTask(r.Tasks.Start(taskID))
+
+	for idx, doc := range job.Docs {
+		// stopped: the task stays unfinished and is resumed on the next start
+		if ctx.Err() != nil {
+			log.Printf("Task %s: interrupted", taskID)
+			return
+		}
+
+		progress := info.Documents[idx]
+
+		// a resumed task keeps the documents it already completed
+		if progress.Status == task.StatusCompleted {
+			continue
+		}
+
+		// an update keeps the ID of its document, the next attempt replaces what an
+		// interrupted one saved
+		if doc.Mode == modeUpdate {
+			log.Printf("Task %s: processing update of document %s (filename: %s)", taskID, doc.ID, doc.Filename)
+			trackTask(r.Tasks.StartDocument(taskID, idx, doc.ID))
+		} else {
+			// remove whatever an interrupted attempt may have saved
+			if progress.DocumentID != "" {
+				if err := r.DeleteDocument(ctx, progress.DocumentID); err != nil {
+					log.Printf("Task %s: failed to clean up document %s: %v", taskID, progress.DocumentID, err)
+				}
+			}
+
+			// Generate a unique ID for each document
+			doc.ID = uuid.NewString()
+			log.Printf("Task %s: processing document %d with generated ID %s (filename: %s)", taskID, idx, doc.ID, doc.Filename)
+			trackTask(r.Tasks.StartDocument(taskID, idx, doc.ID))
+		}
+
+		docID := doc.ID
+		savedID, skipped, err := r.processDocument(ctx, taskID, idx, doc)
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("Task %s: interrupted while processing document %s", taskID, docID)
+				return
+			}
+			log.Printf("Task %s: %v", taskID, err)
+			trackTask(r.Tasks.FailDocument(taskID, idx, err))
+			continue
+		}
+
+		if skipped {
+			trackTask(r.Tasks.SkipDocument(taskID, idx, savedID))
+			continue
+		}
+		trackTask(r.Tasks.CompleteDocument(taskID, idx, savedID))
+	}
+
+	trackTask(r.Tasks.Finish(taskID))
+}
+
+// processDocument chunks, summarizes, vectorizes and saves a single document,
+// recording each step on the task tracker. It returns the ID the document is saved with,
+// which is the ID of the existing document when it replaces one or is skipped.
+func (r *Rag) processDocument(ctx context.Context, taskID string, idx int, doc models.Document) (string, bool, error) {
+	docID := doc.ID
+
+	// extracted files are queued with their sections only
+	if doc.Content == "" && len(doc.Sections) > 0 {
+		doc.Content = extract.Content(doc.Sections)
+	}
+
+	// Step 0: Download the documents uploaded as a link only
+	if doc.Content == "" && doc.Link != "" {
+		log.Printf("Task %s: fetching %s for document %s", taskID, doc.Link, docID)
+		trackTask(r.Tasks.SetStep(taskID, idx, "fetching"))
+		fetched, err := r.fetchDocument(ctx, doc)
+		if err != nil {
+			return "", false, fmt.Errorf("error fetching document %s: %w", docID, err)
+		}
+		doc = fetched
+		log.Printf("Task %s: fetched %s for document %s", taskID, doc.Link, docID)
+	}
+
+	// Step 0.5: Look for the same document already uploaded
+	doc.ContentHash = contentHash(doc.Content)
+	plan, existing, err := r.planDocument(ctx, doc)
+	if err != nil {
+		return "", false, fmt.Errorf("error checking duplicates of document %s: %w", docID, err)
+	}
+	if plan.Skip {
+		log.Printf("Task %s: document %s already uploaded as %s, skipping", taskID, docID, plan.DocumentID)
+		return plan.DocumentID, true, nil
+	}
+	if plan.DocumentID != "" {
+		log.Printf("Task %s: document %s replaces %s with version %d", taskID, docID, plan.DocumentID, plan.Version)
+		docID = plan.DocumentID
+	}
+	// a queued update applies to the document as it is now, with the changes made since it
+	// was queued
+	if doc.Update != nil && existing != nil {
+		doc = applyUpdate(doc, *existing)
+	}
+
+	// Step 1: Create chunks from document content
+	trackTask(r.Tasks.SetStep(taskID, idx, "chunking"))
+	chunking, err := r.ResolveChunking(doc.Chunking)
+	if err != nil {
+		return "", false, fmt.Errorf("error chunking document %s: %w", docID, err)
+	}
+	chunker, err := textprocessor.NewChunker(chunking, r.chunkLimits(), r.Embeddings)
+	if err != nil {
+		return "", false, fmt.Errorf("error chunking document %s: %w", docID, err)
+	}
+	chunks, chunkMetadata, err := documentChunks(ctx, doc, chunker)
+	if err != nil {
+		return "", false, fmt.Errorf("error chunking document %s: %w", docID, err)
+	}
+	log.Printf("Task %s: created %d chunks for document %s", taskID, len(chunks), docID)
+	trackTask(r.Tasks.SetChunks(taskID, idx, len(chunks)))
+
+	// an unchanged content keeps the summary and its vector
+	unchanged := existing != nil && existing.ContentHash == doc.ContentHash && existing.Summary != ""
+
+	// Step 2: Generate summary for the document
+	summary := ""
+	if unchanged {
+		summary = existing.Summary
+	} else {
+		var summaryChunks string
+		if len(chunks) < 4 {
+			summaryChunks = doc.Content
+		} else {
+			summaryChunks = textprocessor.ConcatenateStrings(chunks[:3])
+		}
+
+		log.Printf("Task %s: generating summary for document %s", taskID, docID)
+		trackTask(r.Tasks.SetStep(taskID, idx, "summarizing"))
+		summary, err = r.LLM.Generate(ctx, fmt.Sprintf("Give me only summary of the following text: %s", summaryChunks))
+		if err != nil {
+			return "", false, fmt.Errorf("error generating summary for document %s: %w", docID, err)
+		}
+		log.Printf("Task %s: generated summary for document %s", taskID, docID)
+	}
+
+	// Step 3: Vectorize the summary
+	var summaryVector []float32
+	if !unchanged {
+		log.Printf("Task %s: vectorizing summary for document %s", taskID, docID)
+		trackTask(r.Tasks.SetStep(taskID, idx, "vectorizing"))
+		vectorSum, err := r.Embeddings.Vectorize(ctx, summary)
+		if err != nil {
+			return "", false, fmt.Errorf("error vectorizing summary for document %s: %w", docID, err)
+		}
+		summaryVector = vectorSum[0]
+		log.Printf("Task %s: vectorized summary for document %s", taskID, docID)
+	}
+
+	// Step 4: Process embeddings for the chunks, the service batches the requests. The chunks
+	// of a replaced document whose text is unchanged keep their ID and vector.
+	var replaced []models.Embedding
+	if plan.DocumentID != "" {
+		replaced, err = r.Database.GetEmbeddings(ctx, docID)
+		if err != nil {
+			return "", false, fmt.Errorf("error loading chunks of document %s: %w", docID, err)
+		}
+	}
+	reusable := replaced
+	if existing != nil && existing.EmbeddingModel != r.Embeddings.GetModel() {
+		reusable = nil
+	}
+	matched := matchChunks(chunks, reusable)
+
+	var changed []string
+	for order, chunk := range chunks {
+		if matched[order] == nil {
+			changed = append(changed, chunk)
+		}
+	}
+	log.Printf("Task %s: vectorizing %d of %d chunks for document %s", taskID, len(changed), len(chunks), docID)
+	trackTask(r.Tasks.SetStep(taskID, idx, "vectorizing"))
+	var vectors [][]float32
+	if len(changed) > 0 {
+		vectors, err = r.Embeddings.VectorizeBatch(ctx, changed)
+		if err != nil {
+			return "", false, fmt.Errorf("error vectorizing chunks for document %s: %w", docID, err)
+		}
+		if len(vectors) != len(changed) {
+			return "", false, fmt.Errorf("error vectorizing chunks for document %s: expected %d embeddings, got %d", docID, len(changed), len(vectors))
+		}
+	}
+	log.Printf("Task %s: vectorized %d chunks for document %s", taskID, len(changed), docID)
+
+	// a new version starts now, a rewritten one (a metadata update) keeps its time. The new
+	// chunks of a rewritten version take its place in the past, unless the replaced chunks
+	// are kept for history.
+	now := time.Now().Unix()
+	versionTime := now
+	if existing != nil && existing.Version == plan.Version {
+		versionTime = existing.UpdatedAt
+	}
+	validFrom := versionTime
+	if plan.History {
+		validFrom = now
+	}
+
+	// A previous chunk with the same text at the same position is kept as it is, one that
+	// moved gives its vector to a new chunk so the past versions keep their order.
+	var embeddings []models.Embedding
+	kept := make(map[string]bool)
+	for order, chunk := range chunks {
+		embedding := models.Embedding{
+			DocumentID: docID,
+			TextChunk:  chunk,
+			Order:      int64(order),
+			ValidFrom:  validFrom,
+			Filename:   doc.Filename,
+			Link:       doc.Link,
+			Category:   doc.Category,
+			Metadata:   chunkMetadata[order],
+		}
+		previous := matched[order]
+		switch {
+		case previous != nil && previous.Order == int64(order):
+			embedding.ID = previous.ID
+			embedding.Vector = previous.Vector
+			embedding.ValidFrom = previous.ValidFrom
+			kept[previous.ID] = true
+		case previous != nil:
+			embedding.ID = uuid.NewString()
+			embedding.Vector = previous.Vector
+		default:
+			embedding.ID = uuid.NewString()
+			embedding.Vector = vectors[0]
+			vectors = vectors[1:]
+		}
+		embedding.Dimension = int64(len(embedding.Vector))
+		embeddings = append(embeddings, embedding)
+	}
+
+	// the chunks of the replaced document that are not kept, once the new ones are saved they
+	// are retired, or deleted when the replaced content is not kept
+	var retired []models.Embedding
+	var stale []string
+	for _, chunk := range replaced {
+		if kept[chunk.ID] {
+			continue
+		}
+		if plan.History {
+			chunk.ValidTo = now
+			retired = append(retired, chunk)
+		} else {
+			stale = append(stale, chunk.ID)
+		}
+	}
+
+	// Step 5: Save the document and its embeddings
+	document := models.Document{
+		ID:             docID,
+		Content:        "",
+		Link:           doc.Link,
+		Filename:       doc.Filename,
+		Category:       doc.Category,
+		EmbeddingModel: r.Embeddings.GetModel(),
+		Summary:        summary,
+		Vector:         summaryVector,
+		Metadata:       chunkingMetadata(doc.Metadata, chunking),
+		ExternalID:     doc.ExternalID,
+		ContentHash:    doc.ContentHash,
+		Version:        plan.Version,
+		UpdatedAt:      versionTime,
+	}
+	trackTask(r.Tasks.SetStep(taskID, idx, "saving"))
+
+	// the new chunks and the retired ones are saved in a single upsert, so the latest version
+	// never has both. A failure of the following steps rolls the chunks back.
+	log.Printf("Task %s: saving %d embeddings for document %s", taskID, len(embeddings), docID)
+	if err := r.Database.SaveEmbeddings(ctx, append(embeddings, retired...)); err != nil {
+		return "", false, fmt.Errorf("error saving embeddings for document %s: %w", docID, err)
+	}
+	log.Printf("Task %s: saved embeddings for document %s, retired %d replaced chunks", taskID, docID, len(retired))
+
+	if len(stale) > 0 {
+		if err := r.Database.DeleteEmbeddings(ctx, stale); err != nil {
+			r.rollbackChunks(ctx, taskID, docID, embeddings, replaced, kept)
+			return "", false, fmt.Errorf("error deleting replaced chunks of document %s: %w", docID, err)
+		}
+		log.Printf("Task %s: deleted %d replaced chunks of document %s", taskID, len(stale), docID)
+	}
+
+	log.Printf("Task %s: saving document %s", taskID, docID)
+	if unchanged {
+		err = r.Database.UpdateDocument(ctx, document)
+	} else {
+		err = r.Database.SaveDocument(ctx, document)
+	}
+	if err != nil {
+		r.rollbackChunks(ctx, taskID, docID, embeddings, replaced, kept)
+		return "", false, fmt.Errorf("error saving document %s: %w", docID, err)
+	}
+	log.Printf("Task %s: saved document %s", taskID, docID)
+
+	if r.Keywords != nil {
+		if err := r.Keywords.Add(append(embeddings, retired...)); err != nil {
+			return "", false, fmt.Errorf("error indexing keywords for document %s: %w", docID, err)
+		}
+		if err := r.Keywords.Remove(stale); err != nil {
+			return "", false, fmt.Errorf("error indexing keywords for document %s: %w", docID, err)
+		}
+	}
+
+	// Step 6: Record the version, an unchanged content keeps its record
+	if r.History != nil && !unchanged {
+		version := history.Version{
+			Version:     plan.Version,
+			CreatedAt:   time.Unix(versionTime, 0).UTC(),
+			ContentHash: doc.ContentHash,
+			Summary:     summary,
+			Content:     doc.Content,
+		}
+		if err := r.History.Save(docID, version); err != nil {
+			return "", false, fmt.Errorf("error recording version %d of document %s: %w", plan.Version, docID, err)
+		}
+		log.Printf("Task %s: recorded version %d of document %s", taskID, plan.Version, docID)
+	}
+
+	return docID, false, nil
+}
+
+// rollbackChunks restores the chunks of a document as they were before processDocument saved
+// its new chunks: the new ones are deleted and the replaced ones saved back. It runs even when
+// ctx is cancelled, failures are only logged.
+func (r *Rag) rollbackChunks(ctx context.Context, taskID, docID string, embeddings, replaced []models.Embedding, kept map[string]bool) {
+	ctx = context.WithoutCancel(ctx)
+
+	var added []string
+	for _, embedding := range embeddings {
+		if !kept[embedding.ID] {
+			added = append(added, embedding.ID)
+		}
+	}
+	if err := r.Database.DeleteEmbeddings(ctx, added); err != nil {
+		log.Printf("Task %s: failed to roll back the new chunks of document %s: %v", taskID, docID, err)
+	}
+	if len(replaced) > 0 {
+		if err := r.Database.SaveEmbeddings(ctx, replaced); err != nil {
+			log.Printf("Task %s: failed to restore the replaced chunks of document %s: %v", taskID, docID, err)
+		}
+	}
+	log.Printf("Task %s: rolled back the chunks of document %s", taskID, docID)
+}
+
+// MaxURLLength is the longest URL stored as the link, filename or external ID of a page
+const MaxURLLength = 512
+
+// fetchDocument downloads the link of the document and sets its content. The link becomes
+// the final URL after redirects when it isn't longer than MaxURLLength, the requested URL,
+// final URL, fetch time and content type are added to the metadata.
+func (r *Rag) fetchDocument(ctx context.Context, doc models.Document) (models.Document, error) {
+	page, err := r.Fetcher.Fetch(ctx, doc.Link)
+	if err != nil {
+		return models.Document{}, err
+	}
+
+	metadata := map[string]string{
+		"source_url":   doc.Link,
+		"final_url":    page.URL,
+		"fetched_at":   page.FetchedAt.Format(time.RFC3339),
+		"content_type": page.ContentType,
+	}
+	for key, value := range doc.Metadata {
+		metadata[key] = value
+	}
+
+	doc.Content = extract.Content(page.Sections)
+	doc.Sections = page.Sections
+	if len(page.URL) <= MaxURLLength {
+		doc.Link = page.URL
+	}
+	doc.Metadata = metadata
+	if doc.Filename == "" {
+		doc.Filename = page.Filename
+	}
+	return doc, nil
+}
+
+// ResolveChunking returns the chunking of a document: the given one, or the default Chunking
+// when it is nil or has no strategy, with the size defaults of the strategy
+func (r *Rag) ResolveChunking(chunking *models.Chunking) (models.Chunking, error) {
+	opts := r.Chunking
+	if chunking != nil && chunking.Strategy != "" {
+		opts = *chunking
+	}
+	return textprocessor.Resolve(opts)
+}
+
+// chunkLimits returns the limits of every chunk: the token limit of the embedding model,
+// counted with its tokenizer approximation, and the size limit of the chunks in the database
+func (r *Rag) chunkLimits() textprocessor.Limits {
+	tokenizer, maxTokens := textprocessor.ModelTokenizer(r.Embeddings.GetModel())
+	if r.MaxTokens > 0 {
+		maxTokens = r.MaxTokens
+	}
+	return textprocessor.Limits{Tokenizer: tokenizer, MaxTokens: maxTokens, MaxBytes: database.MaxChunkBytes(r.Database)}
+}
+
+// chunkingMetadata returns a copy of the document metadata recording its chunking
+func chunkingMetadata(metadata map[string]string, chunking models.Chunking) map[string]string {
+	recorded := make(map[string]string, len(metadata)+3)
+	for key, value := range metadata {
+		recorded[key] = value
+	}
+	recorded["chunker"] = chunking.Strategy
+	recorded["chunk_size"] = strconv.Itoa(chunking.Size)
+	recorded["chunk_overlap"] = strconv.Itoa(chunking.Overlap)
+	if chunking.Percentile > 0 {
+		recorded["chunk_percentile"] = strconv.FormatFloat(chunking.Percentile, 'g', -1, 64)
+	}
+	return recorded
+}
+
+// documentChunks splits the document into chunks and returns the metadata of each chunk:
+// the document metadata plus the "page" and "heading" of the extracted section it comes from
+func documentChunks(ctx context.Context, doc models.Document, chunker textprocessor.Chunker) ([]string, []map[string]string, error) {
+	if len(doc.Sections) == 0 {
+		chunks, err := chunker.Chunk(ctx, doc.Content)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata := make([]map[string]string, len(chunks))
+		for i := range chunks {
+			metadata[i] = doc.Metadata
+		}
+		return chunks, metadata, nil
+	}
+
+	var chunks []string
+	var metadata []map[string]string
+	for _, section := range doc.Sections {
+		sectionMetadata := make(map[string]string, len(doc.Metadata)+2)
+		for key, value := range doc.Metadata {
+			sectionMetadata[key] = value
+		}
+		if section.Page > 0 {
+			sectionMetadata["page"] = strconv.Itoa(section.Page)
+		}
+		if section.Heading != "" {
+			sectionMetadata["heading"] = section.Heading
+		}
+
+		sectionChunks, err := chunker.Chunk(ctx, section.Text)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, chunk := range sectionChunks {
+			chunks = append(chunks, chunk)
+			metadata = append(metadata, sectionMetadata)
+		}
+	}
+	return chunks, metadata, nil
+}
+
+// DeleteDocument deletes the document, the chunks of all its versions and its version history
+func (r *Rag) DeleteDocument(ctx context.Context, id string) error {
+	if err := r.Database.DeleteDocument(ctx, id); err != nil {
+		return err
+	}
+
+	if r.Keywords != nil {
+		if err := r.Keywords.RemoveDocument(id); err != nil {
+			return fmt.Errorf("failed to remove document %s from the keyword index: %w", id, err)
+		}
+	}
+
+	if r.History != nil {
+		if err := r.History.Delete(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trackTask logs task tracker failures, which must not stop the processing itself
+func trackTask(err error) {
+	if err != nil {
+		log.Printf("Task tracker error: %v", err)
+	}
+}
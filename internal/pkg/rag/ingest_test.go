@@ -0,0 +1,352 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/task"
+	"github.com/elchemista/easy_rag/internal/pkg/textprocessor"
+)
+
+func TestDocumentChunksKeepSectionMetadata(t *testing.T) {
+	doc := models.Document{
+		Metadata: map[string]string{"tenant": "acme"},
+		Sections: []models.Section{
+			{Text: "First page text.", Page: 1},
+			{Text: "Scope of the standard.", Page: 2, Heading: "Scope"},
+		},
+	}
+
+	chunks, metadata, err := documentChunks(context.Background(), doc, textprocessor.Sentences{Size: textprocessor.DefaultSentenceSize})
+	if err != nil {
+		t.Fatalf("documentChunks() error = %v", err)
+	}
+	if len(chunks) != 2 || len(metadata) != 2 {
+		t.Fatalf("documentChunks() = %q, %v, want one chunk per section", chunks, metadata)
+	}
+	if metadata[0]["page"] != "1" || metadata[0]["heading"] != "" || metadata[0]["tenant"] != "acme" {
+		t.Errorf("metadata[0] = %v, want page 1 and the document metadata", metadata[0])
+	}
+	if metadata[1]["page"] != "2" || metadata[1]["heading"] != "Scope" {
+		t.Errorf("metadata[1] = %v, want page 2 under Scope", metadata[1])
+	}
+	if _, ok := doc.Metadata["page"]; ok {
+		t.Errorf("document metadata was modified: %v", doc.Metadata)
+	}
+}
+
+func TestResolveChunking(t *testing.T) {
+	r := NewRag(nil, nil, nil, nil)
+	r.Chunking = models.Chunking{Strategy: textprocessor.StrategyRecursive, Size: 800, Overlap: 100}
+
+	tests := []struct {
+		chunking *models.Chunking
+		want     models.Chunking
+	}{
+		{nil, r.Chunking},
+		{&models.Chunking{Size: 300}, r.Chunking},
+		{&models.Chunking{Strategy: textprocessor.StrategyToken}, models.Chunking{Strategy: textprocessor.StrategyToken, Size: textprocessor.DefaultTokenSize}},
+	}
+	for _, tt := range tests {
+		got, err := r.ResolveChunking(tt.chunking)
+		if err != nil {
+			t.Fatalf("ResolveChunking(%+v) error = %v", tt.chunking, err)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveChunking(%+v) = %+v, want %+v", tt.chunking, got, tt.want)
+		}
+	}
+
+	if _, err := r.ResolveChunking(&models.Chunking{Strategy: "pages"}); err == nil {
+		t.Error("ResolveChunking() with an unknown strategy returned no error")
+	}
+
+	metadata := map[string]string{"tenant": "acme"}
+	recorded := chunkingMetadata(metadata, r.Chunking)
+	if recorded["chunker"] != "recursive" || recorded["chunk_size"] != "800" || recorded["chunk_overlap"] != "100" || recorded["tenant"] != "acme" {
+		t.Errorf("chunkingMetadata() = %v", recorded)
+	}
+	if len(metadata) != 1 {
+		t.Errorf("document metadata was modified: %v", metadata)
+	}
+}
+
+// limitedDatabase stores chunks of up to 100 bytes
+type limitedDatabase struct {
+	*fakeDatabase
+}
+
+func (limitedDatabase) MaxChunkBytes() int { return 100 }
+
+func TestChunkLimits(t *testing.T) {
+	r := NewRag(nil, fakeEmbeddings{}, &fakeDatabase{}, nil)
+	if limits := r.chunkLimits(); limits.MaxTokens != 0 || limits.MaxBytes != 0 || limits.Tokenizer == nil {
+		t.Errorf("chunkLimits() = %+v, want no limit for an unknown model and an unlimited database", limits)
+	}
+
+	r.Database = limitedDatabase{&fakeDatabase{}}
+	if limits := r.chunkLimits(); limits.MaxBytes != 100 {
+		t.Errorf("chunkLimits().MaxBytes = %d, want the 100 bytes of the database", limits.MaxBytes)
+	}
+
+	r.MaxTokens = 256
+	if limits := r.chunkLimits(); limits.MaxTokens != 256 {
+		t.Errorf("chunkLimits().MaxTokens = %d, want the configured 256", limits.MaxTokens)
+	}
+}
+
+func TestFetchDocument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/page", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/long" {
+			http.Redirect(w, r, "/page?q="+strings.Repeat("x", MaxURLLength), http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<body><nav>Menu</nav><article><h1>Title</h1><p>Body.</p></article></body>`)
+	}))
+	defer server.Close()
+
+	r := NewRag(nil, nil, nil, nil)
+	r.Fetcher.AllowedNetworks = []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	doc, err := r.fetchDocument(context.Background(), models.Document{
+		Link:     server.URL + "/moved",
+		Metadata: map[string]string{"tenant": "acme"},
+	})
+	if err != nil {
+		t.Fatalf("fetchDocument() error = %v", err)
+	}
+
+	if doc.Content != "Title\nBody." || len(doc.Sections) != 1 {
+		t.Errorf("Content = %q, want the article text", doc.Content)
+	}
+	if doc.Link != server.URL+"/page" || doc.Filename != "page" {
+		t.Errorf("Link = %s, Filename = %s, want the final URL", doc.Link, doc.Filename)
+	}
+	if doc.Metadata["source_url"] != server.URL+"/moved" || doc.Metadata["final_url"] != server.URL+"/page" ||
+		doc.Metadata["fetched_at"] == "" || doc.Metadata["tenant"] != "acme" {
+		t.Errorf("Metadata = %v", doc.Metadata)
+	}
+
+	// a final URL too long to be stored leaves the requested one as the link
+	doc, err = r.fetchDocument(context.Background(), models.Document{Link: server.URL + "/long"})
+	if err != nil {
+		t.Fatalf("fetchDocument() error = %v", err)
+	}
+	if doc.Link != server.URL+"/long" || len(doc.Metadata["final_url"]) <= MaxURLLength {
+		t.Errorf("Link = %s, want the requested URL", doc.Link)
+	}
+}
+
+// fakeLLM answers every prompt with the same summary
+type fakeLLM struct{}
+
+func (fakeLLM) Generate(ctx context.Context, prompt string) (string, error) { return "summary", nil }
+func (fakeLLM) GetModel() string                                            { return "fake" }
+
+// recordingEmbeddings records the texts of each VectorizeBatch call
+type recordingEmbeddings struct {
+	fakeEmbeddings
+	batches [][]string
+}
+
+func (e *recordingEmbeddings) VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	e.batches = append(e.batches, texts)
+	return e.fakeEmbeddings.VectorizeBatch(ctx, texts)
+}
+
+// failingDocuments fails to save the documents
+type failingDocuments struct {
+	*fakeDatabase
+}
+
+func (failingDocuments) SaveDocument(ctx context.Context, document models.Document) error {
+	return errors.New("unavailable")
+}
+
+// ingestFixture returns a rag with document d1 at version 1, uploaded at 1000 with the
+// chunks "Intro section." and "Refunds: 90 days." vectorized as {0, 1}
+func ingestFixture(t *testing.T) (*Rag, *fakeDatabase, *recordingEmbeddings) {
+	t.Helper()
+
+	content := "Intro section. Refunds: 90 days."
+	db := &fakeDatabase{
+		docs: map[string]models.Document{
+			"d1": {ID: "d1", ExternalID: "policy", ContentHash: contentHash(content), Version: 1, UpdatedAt: 1000, EmbeddingModel: "fake", Summary: "old"},
+		},
+		chunks: []models.Embedding{
+			{ID: "a0", DocumentID: "d1", Order: 0, TextChunk: "Intro section.", Vector: []float32{0, 1}, ValidFrom: 1000},
+			{ID: "a1", DocumentID: "d1", Order: 1, TextChunk: "Refunds: 90 days.", Vector: []float32{0, 1}, ValidFrom: 1000},
+		},
+	}
+	embeddings := &recordingEmbeddings{}
+
+	tasks, err := task.NewTracker("")
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	if _, err := tasks.Create("task", []string{"policy.txt"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	r := NewRag(fakeLLM{}, embeddings, db, tasks)
+	r.Chunking = models.Chunking{Strategy: textprocessor.StrategySentence, Size: 20}
+	return r, db, embeddings
+}
+
+func TestProcessDocumentModes(t *testing.T) {
+	upload := models.Document{ID: "new", ExternalID: "policy", Filename: "policy.txt", Content: "Intro section. Refunds: 180 days."}
+
+	t.Run("skip", func(t *testing.T) {
+		r, db, embeddings := ingestFixture(t)
+		doc := upload
+		doc.Mode = ModeSkip
+
+		id, skipped, err := r.processDocument(context.Background(), "task", 0, doc)
+		if err != nil {
+			t.Fatalf("processDocument() error = %v", err)
+		}
+		if id != "d1" || !skipped || len(embeddings.batches) != 0 || len(db.chunks) != 2 {
+			t.Errorf("processDocument() = %s, %v, batches %q, want d1 skipped without vectorizing", id, skipped, embeddings.batches)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		r, db, embeddings := ingestFixture(t)
+		doc := upload
+		doc.Mode = ModeReplace
+
+		id, skipped, err := r.processDocument(context.Background(), "task", 0, doc)
+		if err != nil || id != "d1" || skipped {
+			t.Fatalf("processDocument() = %s, %v, %v, want d1 replaced", id, skipped, err)
+		}
+		if want := [][]string{{"Refunds: 180 days."}}; !reflect.DeepEqual(embeddings.batches, want) {
+			t.Errorf("VectorizeBatch() texts = %q, want only the changed chunk", embeddings.batches)
+		}
+
+		// the replaced chunk is deleted, the new one starts version 2
+		chunks := chunkIDs(db.chunks)
+		if len(db.chunks) != 2 || chunks["a0"] == nil || chunks["a1"] != nil {
+			t.Fatalf("chunks = %+v, want a0 and a new chunk", db.chunks)
+		}
+		if a0 := chunks["a0"]; !reflect.DeepEqual(a0.Vector, []float32{0, 1}) || a0.ValidFrom != 1000 {
+			t.Errorf("kept chunk = %+v, want its vector and ValidFrom", a0)
+		}
+		saved := db.docs["d1"]
+		if saved.Version != 2 || saved.UpdatedAt <= 1000 || saved.Summary != "summary" {
+			t.Errorf("document = %+v, want version 2 with the new summary", saved)
+		}
+		for _, chunk := range db.chunks {
+			if chunk.Order == 1 && (chunk.TextChunk != "Refunds: 180 days." || chunk.ValidFrom != saved.UpdatedAt || chunk.ValidTo != 0) {
+				t.Errorf("new chunk = %+v, want the new text valid from %d", chunk, saved.UpdatedAt)
+			}
+		}
+	})
+
+	t.Run("new_version", func(t *testing.T) {
+		r, db, embeddings := ingestFixture(t)
+		doc := upload
+		doc.Mode = ModeNewVersion
+
+		if _, _, err := r.processDocument(context.Background(), "task", 0, doc); err != nil {
+			t.Fatalf("processDocument() error = %v", err)
+		}
+		if want := [][]string{{"Refunds: 180 days."}}; !reflect.DeepEqual(embeddings.batches, want) {
+			t.Errorf("VectorizeBatch() texts = %q, want only the changed chunk", embeddings.batches)
+		}
+
+		// the replaced chunk is retired and stays searchable in the past
+		chunks := chunkIDs(db.chunks)
+		if len(db.chunks) != 3 || chunks["a0"] == nil || chunks["a1"] == nil {
+			t.Fatalf("chunks = %+v, want a0, a1 and a new chunk", db.chunks)
+		}
+		if a0 := chunks["a0"]; !reflect.DeepEqual(a0.Vector, []float32{0, 1}) || a0.ValidFrom != 1000 || a0.ValidTo != 0 {
+			t.Errorf("kept chunk = %+v, want its vector and ValidFrom", a0)
+		}
+		a1 := chunks["a1"]
+		if a1.ValidFrom != 1000 || a1.ValidTo <= 1000 {
+			t.Errorf("retired chunk = %+v, want ValidTo set", a1)
+		}
+		for _, chunk := range db.chunks {
+			if chunk.Order == 1 && chunk.ID != "a1" && chunk.ValidFrom != a1.ValidTo {
+				t.Errorf("new chunk = %+v, want valid from %d", chunk, a1.ValidTo)
+			}
+		}
+		if saved := db.docs["d1"]; saved.Version != 2 || saved.UpdatedAt != a1.ValidTo {
+			t.Errorf("document = %+v, want version 2", saved)
+		}
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		r, db, _ := ingestFixture(t)
+		r.Database = failingDocuments{db}
+		doc := upload
+		doc.Mode = ModeNewVersion
+
+		if _, _, err := r.processDocument(context.Background(), "task", 0, doc); err == nil {
+			t.Fatal("processDocument() error = nil, want the failure to save the document")
+		}
+
+		// the new chunk is deleted and a1 is current again
+		chunks := chunkIDs(db.chunks)
+		if len(db.chunks) != 2 || chunks["a0"] == nil || chunks["a1"] == nil || chunks["a1"].ValidTo != 0 {
+			t.Errorf("chunks = %+v, want a0 and a1 as before", db.chunks)
+		}
+	})
+}
+
+func TestProcessDocumentRebuildsContent(t *testing.T) {
+	r, db, _ := ingestFixture(t)
+	sections := []models.Section{{Text: "Intro section.", Page: 1}, {Text: "Refunds: 180 days.", Page: 2}}
+
+	// an extracted file is queued with its sections only
+	id, _, err := r.processDocument(context.Background(), "task", 0, models.Document{ID: "new", Filename: "policy.pdf", Sections: sections})
+	if err != nil {
+		t.Fatalf("processDocument() error = %v", err)
+	}
+	if saved := db.docs[id]; id != "new" || saved.ContentHash != contentHash("Intro section.\n\nRefunds: 180 days.") {
+		t.Errorf("document %s = %+v, want the hash of the sections text", id, saved)
+	}
+	if chunks, _ := db.GetEmbeddings(context.Background(), "new"); len(chunks) != 2 || chunks[1].Metadata["page"] != "2" {
+		t.Errorf("chunks = %+v, want one per section", chunks)
+	}
+}
+
+func TestProcessUpdateKeepsMetadataUpdatedMeanwhile(t *testing.T) {
+	r, db, _ := ingestFixture(t)
+	fields := models.FieldUpdate{Category: ptr("Policies"), Metadata: map[string]*string{"owner": ptr("legal")}}
+	queued := applyUpdate(models.Document{ID: "d1", Content: "Intro section. Refunds: 180 days.", Mode: modeUpdate, Update: &fields}, db.docs["d1"])
+
+	// the metadata is updated while the new content is queued
+	patched := db.docs["d1"]
+	patched.Metadata = map[string]string{"tenant": "acme"}
+	db.docs["d1"] = patched
+
+	if _, _, err := r.processDocument(context.Background(), "task", 0, queued); err != nil {
+		t.Fatalf("processDocument() error = %v", err)
+	}
+	saved := db.docs["d1"]
+	if saved.Category != "Policies" || saved.Metadata["tenant"] != "acme" || saved.Metadata["owner"] != "legal" {
+		t.Errorf("document = %s %v, want both metadata updates", saved.Category, saved.Metadata)
+	}
+}
+
+// chunkIDs indexes the chunks by ID
+func chunkIDs(chunks []models.Embedding) map[string]*models.Embedding {
+	byID := make(map[string]*models.Embedding, len(chunks))
+	for i := range chunks {
+		byID[chunks[i].ID] = &chunks[i]
+	}
+	return byID
+}
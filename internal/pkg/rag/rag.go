@@ -4,18 +4,47 @@ import (
 	"github.com/elchemista/easy_rag/internal/database"
 	"github.com/elchemista/easy_rag/internal/embeddings"
 	"github.com/elchemista/easy_rag/internal/llm"
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/bm25"
+	"github.com/elchemista/easy_rag/internal/pkg/extract"
+	"github.com/elchemista/easy_rag/internal/pkg/fetch"
+	"github.com/elchemista/easy_rag/internal/pkg/history"
+	"github.com/elchemista/easy_rag/internal/pkg/queue"
+	"github.com/elchemista/easy_rag/internal/pkg/task"
+	"github.com/elchemista/easy_rag/internal/rerank"
 )
 
 type Rag struct {
 	LLM        llm.LLMService
 	Embeddings embeddings.EmbeddingsService
 	Database   database.Database
+	Tasks      *task.Tracker
+	Extractors *extract.Registry // Text extractors of the uploaded files
+	Fetcher    *fetch.Fetcher    // Downloads the documents uploaded with a link and no content
+	Chunking   models.Chunking   // Chunking of the documents uploaded without one
+	MaxTokens  int               // Token limit of a chunk, 0 is the context size of the embedding model
+	UploadMode string            // What to do with a document already uploaded, for uploads without a mode
+	History    *history.Store    // Versions of the documents, nil disables the version records
+	Context    ContextOptions    // How retrieved chunks are packed into the prompt
+	MinScore   float32           // Default minimum vector similarity of the retrieved chunks, 0 keeps all
+
+	Keywords      *bm25.Index // Keyword index of the chunks, nil disables hybrid retrieval
+	KeywordWeight float64     // Default share of the keyword search in hybrid retrieval (0 to 1)
+
+	Reranker rerank.Reranker // Rescores the retrieved chunks, nil disables reranking
+	Rerank   RerankOptions
+
+	queue *queue.Pool
 }
 
-func NewRag(llm llm.LLMService, embeddings embeddings.EmbeddingsService, database database.Database) *Rag {
+func NewRag(llm llm.LLMService, embeddings embeddings.EmbeddingsService, database database.Database, tasks *task.Tracker) *Rag {
 	return &Rag{
 		LLM:        llm,
 		Embeddings: embeddings,
 		Database:   database,
+		Tasks:      tasks,
+		Extractors: extract.NewRegistry(),
+		Fetcher:    fetch.NewFetcher(fetch.DefaultTimeout, fetch.DefaultMaxBytes),
+		UploadMode: ModeSkip,
 	}
 }
@@ -0,0 +1,61 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// RerankOptions controls the reranking of the retrieved chunks
+type RerankOptions struct {
+	Candidates int     // Chunks retrieved and given to the reranker, DefaultTopK if 0
+	TopN       int     // Chunks kept after reranking, DefaultTopK if 0
+	MinScore   float32 // Chunks with a lower rerank score are dropped
+}
+
+// rerank rescores the chunks with the reranker and keeps the best ones. The rerank
+// score replaces the Score of the chunks.
+func (r *Rag) rerank(ctx context.Context, question string, chunks []models.Embedding) ([]models.Embedding, error) {
+	if len(chunks) == 0 {
+		return chunks, nil
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.TextChunk
+	}
+
+	scores, err := r.Reranker.Rerank(ctx, question, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rerank chunks: %w", err)
+	}
+	if len(scores) != len(chunks) {
+		return nil, fmt.Errorf("reranker returned %d scores for %d chunks", len(scores), len(chunks))
+	}
+
+	reranked := make([]models.Embedding, 0, len(chunks))
+	for i, chunk := range chunks {
+		if scores[i] < r.Rerank.MinScore {
+			continue
+		}
+		chunk.Score = scores[i]
+		reranked = append(reranked, chunk)
+	}
+
+	// stable: equal rerank scores keep the retrieval order
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	topN := r.Rerank.TopN
+	if topN <= 0 {
+		topN = DefaultTopK
+	}
+	if len(reranked) > topN {
+		reranked = reranked[:topN]
+	}
+
+	return reranked, nil
+}
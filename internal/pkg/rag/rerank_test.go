@@ -0,0 +1,46 @@
+package rag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// lengthReranker scores the chunks by the length of their text
+type lengthReranker struct{}
+
+func (lengthReranker) Rerank(ctx context.Context, query string, documents []string) ([]float32, error) {
+	scores := make([]float32, len(documents))
+	for i, document := range documents {
+		scores[i] = float32(len(document))
+	}
+	return scores, nil
+}
+
+func TestRetrieveRerank(t *testing.T) {
+	db := &fakeDatabase{
+		chunks: []models.Embedding{
+			{ID: "short", DocumentID: "d1", TextChunk: "a", Score: 0.9},
+			{ID: "long", DocumentID: "d1", TextChunk: "aaaa", Score: 0.8},
+			{ID: "medium", DocumentID: "d1", TextChunk: "aaa", Score: 0.7},
+			{ID: "tiny", DocumentID: "d1", TextChunk: "", Score: 0.6},
+		},
+	}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+	r.Reranker = lengthReranker{}
+	r.Rerank = RerankOptions{Candidates: 3, TopN: 2, MinScore: 1}
+
+	chunks, err := r.Retrieve(context.Background(), "question", RetrieveOptions{})
+	if err != nil {
+		t.Fatalf("Retrieve() error = %v", err)
+	}
+
+	// "tiny" is not a candidate, "short" is cut by TopN
+	if len(chunks) != 2 || chunks[0].ID != "long" || chunks[1].ID != "medium" {
+		t.Errorf("Retrieve() = %v, want long then medium", chunks)
+	}
+	if chunks[0].Score != 4 {
+		t.Errorf("Score = %v, want the rerank score 4", chunks[0].Score)
+	}
+}
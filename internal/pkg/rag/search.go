@@ -0,0 +1,144 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+const (
+	// DefaultTopK is the number of chunks retrieved when none is requested
+	DefaultTopK = 10
+	// MaxTopK bounds the number of chunks a search can return
+	MaxTopK = 100
+	// MaxNeighbours bounds the neighbouring chunks returned on each side of a hit
+	MaxNeighbours = 5
+)
+
+// ErrEmptySearch is returned when a search has neither a query nor a vector
+var ErrEmptySearch = errors.New("search requires a query or a vector")
+
+// SearchQuery describes a semantic search over the stored chunks
+type SearchQuery struct {
+	Query      string        // Text to vectorize, ignored when Vector is set
+	Vector     []float32     // Raw query vector, must match the embedding dimension
+	TopK       int           // Number of chunks to retrieve, DefaultTopK if 0
+	Filter     models.Filter // Restricts the search to the matching documents
+	MinScore   float32       // Chunks scoring below are dropped
+	Neighbours int           // Chunks returned before and after each hit, in document order
+}
+
+// SearchHit is a chunk returned by a search with its document and surrounding text
+type SearchHit struct {
+	ChunkID    string          `json:"chunk_id"`
+	DocumentID string          `json:"document_id"`
+	Order      int64           `json:"order"` // Position of the chunk in the document
+	Score      float32         `json:"score"`
+	Text       string          `json:"text"`
+	Document   models.Document `json:"document"` // Document the chunk belongs to (without content)
+	Before     []string        `json:"before"`   // Text of the preceding chunks, in document order
+	After      []string        `json:"after"`    // Text of the following chunks, in document order
+}
+
+// Search returns the chunks most similar to the query, best first, without calling the LLM
+func (r *Rag) Search(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	if query.TopK < 0 || query.TopK > MaxTopK {
+		return nil, fmt.Errorf("top_k must be between 1 and %d", MaxTopK)
+	}
+	if query.TopK == 0 {
+		query.TopK = DefaultTopK
+	}
+	if query.Neighbours < 0 || query.Neighbours > MaxNeighbours {
+		return nil, fmt.Errorf("neighbours must be between 0 and %d", MaxNeighbours)
+	}
+
+	vector := query.Vector
+	if len(vector) == 0 {
+		if query.Query == "" {
+			return nil, ErrEmptySearch
+		}
+
+		vectors, err := r.Embeddings.Vectorize(ctx, query.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to vectorize query: %w", err)
+		}
+		vector = vectors[0]
+	}
+
+	chunks, err := r.Database.Search(ctx, [][]float32{vector}, query.TopK, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := r.documents(ctx, chunks)
+
+	hits := make([]SearchHit, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.Score < query.MinScore {
+			continue
+		}
+
+		hit := SearchHit{
+			ChunkID:    chunk.ID,
+			DocumentID: chunk.DocumentID,
+			Order:      chunk.Order,
+			Score:      chunk.Score,
+			Text:       chunk.TextChunk,
+			Document:   docs[chunk.DocumentID],
+			Before:     []string{},
+			After:      []string{},
+		}
+
+		if query.Neighbours > 0 {
+			neighbours, err := r.Database.GetChunks(ctx, chunk.DocumentID, chunk.Order-int64(query.Neighbours), chunk.Order+int64(query.Neighbours), query.Filter.AsOf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get chunks around %s: %w", chunk.ID, err)
+			}
+			hit.Before, hit.After = splitNeighbours(chunk.Order, neighbours)
+		}
+
+		hits = append(hits, hit)
+	}
+
+	return hits, nil
+}
+
+// documents loads the documents of the chunks, falling back to the bare ID when one can't be read
+func (r *Rag) documents(ctx context.Context, chunks []models.Embedding) map[string]models.Document {
+	docs := make(map[string]models.Document)
+	for _, chunk := range chunks {
+		if _, ok := docs[chunk.DocumentID]; ok {
+			continue
+		}
+
+		doc, err := r.Database.GetDocumentInfo(ctx, chunk.DocumentID)
+		if err != nil {
+			log.Printf("failed to get document %s: %v", chunk.DocumentID, err)
+			doc = models.Document{ID: chunk.DocumentID}
+		}
+		docs[chunk.DocumentID] = doc
+	}
+	return docs
+}
+
+// splitNeighbours returns the texts of the chunks before and after order, in document order
+func splitNeighbours(order int64, chunks []models.Embedding) (before []string, after []string) {
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Order < chunks[j].Order
+	})
+
+	before, after = []string{}, []string{}
+	for _, chunk := range chunks {
+		switch {
+		case chunk.Order < order:
+			before = append(before, chunk.TextChunk)
+		case chunk.Order > order:
+			after = append(after, chunk.TextChunk)
+		}
+	}
+	return before, after
+}
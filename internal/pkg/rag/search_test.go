@@ -0,0 +1,222 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// fakeDatabase keeps the documents and chunks in memory, returning the chunks with a score as
+// search results
+type fakeDatabase struct {
+	chunks []models.Embedding
+	docs   map[string]models.Document
+}
+
+func (f *fakeDatabase) SaveDocument(ctx context.Context, document models.Document) error {
+	return f.UpdateDocument(ctx, document)
+}
+func (f *fakeDatabase) UpdateDocument(ctx context.Context, document models.Document) error {
+	if f.docs == nil {
+		f.docs = make(map[string]models.Document)
+	}
+	f.docs[document.ID] = document
+	return nil
+}
+func (f *fakeDatabase) GetDocumentInfo(ctx context.Context, id string) (models.Document, error) {
+	return f.docs[id], nil
+}
+func (f *fakeDatabase) GetDocument(ctx context.Context, id string) (models.Document, error) {
+	return f.docs[id], nil
+}
+func (f *fakeDatabase) Search(ctx context.Context, vector [][]float32, topK int, filter models.Filter) ([]models.Embedding, error) {
+	var results []models.Embedding
+	for _, chunk := range f.chunks {
+		if chunk.Score > 0 && chunk.ValidAt(filter.AsOf) && len(results) < topK {
+			results = append(results, chunk)
+		}
+	}
+	return results, nil
+}
+func (f *fakeDatabase) ListDocuments(ctx context.Context) ([]models.Document, error) { return nil, nil }
+func (f *fakeDatabase) FindDocuments(ctx context.Context, externalID, contentHash string) ([]models.Document, error) {
+	var docs []models.Document
+	for _, doc := range f.docs {
+		if (externalID != "" && doc.ExternalID == externalID) || (externalID == "" && doc.ContentHash == contentHash) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+func (f *fakeDatabase) DeleteDocument(ctx context.Context, id string) error { return nil }
+func (f *fakeDatabase) SaveEmbeddings(ctx context.Context, embeddings []models.Embedding) error {
+	for _, embedding := range embeddings {
+		found := false
+		for i, chunk := range f.chunks {
+			if chunk.ID == embedding.ID {
+				f.chunks[i] = embedding
+				found = true
+			}
+		}
+		if !found {
+			f.chunks = append(f.chunks, embedding)
+		}
+	}
+	return nil
+}
+func (f *fakeDatabase) DeleteEmbeddings(ctx context.Context, ids []string) error {
+	var chunks []models.Embedding
+	for _, chunk := range f.chunks {
+		if !contains(ids, chunk.ID) {
+			chunks = append(chunks, chunk)
+		}
+	}
+	f.chunks = chunks
+	return nil
+}
+func (f *fakeDatabase) GetChunks(ctx context.Context, documentID string, from, to int64, asOf *time.Time) ([]models.Embedding, error) {
+	var chunks []models.Embedding
+	for _, chunk := range f.chunks {
+		if chunk.DocumentID == documentID && chunk.Order >= from && chunk.Order <= to && chunk.ValidAt(asOf) {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+func (f *fakeDatabase) GetChunksByID(ctx context.Context, ids []string, filter models.Filter) ([]models.Embedding, error) {
+	var chunks []models.Embedding
+	for _, chunk := range f.chunks {
+		if contains(ids, chunk.ID) && filter.Match(chunk) {
+			chunk.Vector = nil
+			chunk.Score = 0
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+func (f *fakeDatabase) GetEmbeddings(ctx context.Context, documentID string) ([]models.Embedding, error) {
+	return f.GetChunks(ctx, documentID, 0, math.MaxInt64, nil)
+}
+
+func (f *fakeDatabase) CountChunks(ctx context.Context) (int, error) { return len(f.chunks), nil }
+
+// ScanChunks returns the chunks two at a time, as pages
+func (f *fakeDatabase) ScanChunks(ctx context.Context, fn func(chunks []models.Embedding) error) error {
+	for i := 0; i < len(f.chunks); i += 2 {
+		if err := fn(f.chunks[i:min(i+2, len(f.chunks))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeEmbeddings struct{}
+
+func (fakeEmbeddings) Vectorize(ctx context.Context, text string) ([][]float32, error) {
+	return [][]float32{{1, 0}}, nil
+}
+func (fakeEmbeddings) VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+func (fakeEmbeddings) GetModel() string { return "fake" }
+
+func TestSearch(t *testing.T) {
+	db := &fakeDatabase{
+		chunks: []models.Embedding{
+			{ID: "c3", DocumentID: "d1", Order: 3, TextChunk: "fourth"},
+			{ID: "c1", DocumentID: "d1", Order: 1, TextChunk: "second", Score: 0.9},
+			{ID: "c0", DocumentID: "d1", Order: 0, TextChunk: "first"},
+			{ID: "c2", DocumentID: "d1", Order: 2, TextChunk: "third"},
+			{ID: "e0", DocumentID: "d2", Order: 0, TextChunk: "other", Score: 0.2},
+		},
+		docs: map[string]models.Document{
+			"d1": {ID: "d1", Filename: "one.txt"},
+			"d2": {ID: "d2", Filename: "two.txt"},
+		},
+	}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+
+	hits, err := r.Search(context.Background(), SearchQuery{Query: "second", MinScore: 0.5, Neighbours: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 1 {
+		t.Fatalf("Search() returned %d hits, want 1 above the minimum score", len(hits))
+	}
+
+	hit := hits[0]
+	if hit.ChunkID != "c1" || hit.Document.Filename != "one.txt" {
+		t.Errorf("hit = %+v, want chunk c1 of one.txt", hit)
+	}
+	if !reflect.DeepEqual(hit.Before, []string{"first"}) || !reflect.DeepEqual(hit.After, []string{"third"}) {
+		t.Errorf("neighbours = %q / %q, want [first] / [third]", hit.Before, hit.After)
+	}
+}
+
+func TestSearchValidation(t *testing.T) {
+	r := NewRag(nil, fakeEmbeddings{}, &fakeDatabase{}, nil)
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+	}{
+		{"no query", SearchQuery{}},
+		{"top_k too large", SearchQuery{Query: "q", TopK: MaxTopK + 1}},
+		{"negative neighbours", SearchQuery{Query: "q", Neighbours: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := r.Search(context.Background(), tt.query); err == nil {
+				t.Errorf("Search() error = nil, want an error")
+			}
+		})
+	}
+
+	if _, err := r.Search(context.Background(), SearchQuery{}); !errors.Is(err, ErrEmptySearch) {
+		t.Errorf("Search() error = %v, want ErrEmptySearch", err)
+	}
+}
+
+func TestSearchAsOf(t *testing.T) {
+	// version 2 at 2000 replaced the second chunk and moved the third one
+	db := &fakeDatabase{
+		chunks: []models.Embedding{
+			{ID: "a0", DocumentID: "d1", Order: 0, TextChunk: "intro", ValidFrom: 1000},
+			{ID: "a1", DocumentID: "d1", Order: 1, TextChunk: "90 days", Score: 0.9, ValidFrom: 1000, ValidTo: 2000},
+			{ID: "a2", DocumentID: "d1", Order: 2, TextChunk: "outro", ValidFrom: 1000, ValidTo: 2000},
+			{ID: "b1", DocumentID: "d1", Order: 1, TextChunk: "180 days", Score: 0.8, ValidFrom: 2000},
+			{ID: "b2", DocumentID: "d1", Order: 2, TextChunk: "new section", ValidFrom: 2000},
+			{ID: "b3", DocumentID: "d1", Order: 3, TextChunk: "outro", ValidFrom: 2000},
+		},
+		docs: map[string]models.Document{"d1": {ID: "d1"}},
+	}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+
+	hits, err := r.Search(context.Background(), SearchQuery{Query: "days", Neighbours: 1})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 1 || hits[0].ChunkID != "b1" || !reflect.DeepEqual(hits[0].After, []string{"new section"}) {
+		t.Errorf("Search() = %+v, want b1 of the latest version", hits)
+	}
+
+	asOf := time.Unix(1500, 0)
+	hits, err = r.Search(context.Background(), SearchQuery{Query: "days", Neighbours: 1, Filter: models.Filter{AsOf: &asOf}})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 1 || hits[0].ChunkID != "a1" || !reflect.DeepEqual(hits[0].After, []string{"outro"}) {
+		t.Errorf("Search() as of 1500 = %+v, want a1 of the first version", hits)
+	}
+}
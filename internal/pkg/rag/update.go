@@ -0,0 +1,202 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/task"
+)
+
+// modeUpdate marks the queued content of an existing document, whose ID is the document ID
+const modeUpdate = "update"
+
+var (
+	// chunkingKeys are the metadata keys recorded on a document by its ingestion
+	chunkingKeys = []string{"chunker", "chunk_size", "chunk_overlap", "chunk_percentile"}
+	// sectionKeys are the metadata keys recorded on a chunk from the section it comes from
+	sectionKeys = []string{"page", "heading"}
+)
+
+// DocumentUpdate changes the content and fields of an existing document, nil fields keep their value
+type DocumentUpdate struct {
+	models.FieldUpdate
+	Content  *string          // New content, re-indexed by EnqueueUpdate
+	Chunking *models.Chunking // Chunking of the new content, the one of the document if nil
+}
+
+// UpdateMetadata changes the link, filename, category and metadata of a document and of its
+// chunks, their vectors are kept as they are
+func (r *Rag) UpdateMetadata(ctx context.Context, id string, update DocumentUpdate) (models.Document, error) {
+	doc, err := r.Database.GetDocumentInfo(ctx, id)
+	if err != nil {
+		return models.Document{}, err
+	}
+	if doc.ID == "" {
+		return models.Document{}, fmt.Errorf("%w: %s", database.ErrNotFound, id)
+	}
+
+	updateFields(&doc, update.FieldUpdate)
+	doc.Metadata = updateMetadata(doc.Metadata, update.FieldUpdate, chunkingKeys)
+
+	chunks, err := r.Database.GetEmbeddings(ctx, id)
+	if err != nil {
+		return models.Document{}, fmt.Errorf("failed to load chunks of document %s: %w", id, err)
+	}
+	for i := range chunks {
+		chunks[i].Link = doc.Link
+		chunks[i].Filename = doc.Filename
+		chunks[i].Category = doc.Category
+		chunks[i].Metadata = updateMetadata(chunks[i].Metadata, update.FieldUpdate, sectionKeys)
+	}
+
+	if len(chunks) > 0 {
+		if err := r.Database.SaveEmbeddings(ctx, chunks); err != nil {
+			return models.Document{}, fmt.Errorf("failed to save chunks of document %s: %w", id, err)
+		}
+	}
+	if err := r.Database.UpdateDocument(ctx, doc); err != nil {
+		return models.Document{}, fmt.Errorf("failed to save document %s: %w", id, err)
+	}
+
+	return doc, nil
+}
+
+// EnqueueUpdate queues the new content of a document for ingestion under the ID of the
+// document. Only the chunks whose text changed are embedded again. The other fields of the
+// update are applied when the content is processed, so the metadata updated meanwhile is kept.
+func (r *Rag) EnqueueUpdate(ctx context.Context, id string, update DocumentUpdate) (task.Task, error) {
+	if update.Content == nil {
+		return task.Task{}, fmt.Errorf("no content to update document %s with", id)
+	}
+
+	doc, err := r.Database.GetDocumentInfo(ctx, id)
+	if err != nil {
+		return task.Task{}, err
+	}
+	if doc.ID == "" {
+		return task.Task{}, fmt.Errorf("%w: %s", database.ErrNotFound, id)
+	}
+
+	// the same chunking makes the unchanged parts of the content give the same chunks
+	chunking := update.Chunking
+	if chunking == nil {
+		chunking = recordedChunking(doc.Metadata)
+	}
+	resolved, err := r.ResolveChunking(chunking)
+	if err != nil {
+		return task.Task{}, err
+	}
+
+	fields := update.FieldUpdate
+	return r.Enqueue([]models.Document{applyUpdate(models.Document{
+		ID:         id,
+		Content:    *update.Content,
+		Chunking:   &resolved,
+		ExternalID: doc.ExternalID,
+		Mode:       modeUpdate,
+		Update:     &fields,
+	}, doc)})
+}
+
+// applyUpdate sets the link, filename, category and metadata of the queued update to the
+// ones of the document with the update applied
+func applyUpdate(queued models.Document, doc models.Document) models.Document {
+	queued.Link, queued.Filename, queued.Category = doc.Link, doc.Filename, doc.Category
+	updateFields(&queued, *queued.Update)
+	queued.Metadata = updateMetadata(withoutKeys(doc.Metadata, chunkingKeys), *queued.Update, nil)
+	return queued
+}
+
+// updateFields sets the link, filename and category of the update on the document
+func updateFields(doc *models.Document, update models.FieldUpdate) {
+	if update.Link != nil {
+		doc.Link = *update.Link
+	}
+	if update.Filename != nil {
+		doc.Filename = *update.Filename
+	}
+	if update.Category != nil {
+		doc.Category = *update.Category
+	}
+}
+
+// updateMetadata returns a copy of the metadata with the update applied. A replaced metadata
+// keeps the given keys, which are recorded by the ingestion.
+func updateMetadata(metadata map[string]string, update models.FieldUpdate, keep []string) map[string]string {
+	updated := make(map[string]string, len(metadata)+len(update.Metadata))
+	for key, value := range metadata {
+		if !update.ReplaceMetadata || contains(keep, key) {
+			updated[key] = value
+		}
+	}
+	for key, value := range update.Metadata {
+		if value == nil {
+			delete(updated, key)
+			continue
+		}
+		updated[key] = *value
+	}
+	return updated
+}
+
+// withoutKeys returns a copy of the metadata without the given keys
+func withoutKeys(metadata map[string]string, keys []string) map[string]string {
+	copied := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if !contains(keys, key) {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
+// recordedChunking returns the chunking recorded in the metadata of a document, nil if none
+func recordedChunking(metadata map[string]string) *models.Chunking {
+	if metadata["chunker"] == "" {
+		return nil
+	}
+	chunking := &models.Chunking{Strategy: metadata["chunker"]}
+	chunking.Size, _ = strconv.Atoi(metadata["chunk_size"])
+	chunking.Overlap, _ = strconv.Atoi(metadata["chunk_overlap"])
+	chunking.Percentile, _ = strconv.ParseFloat(metadata["chunk_percentile"], 64)
+	return chunking
+}
+
+// matchChunks pairs each chunk with a previous chunk of the same text, by hash, each previous
+// chunk being used once in document order. It returns the match of each chunk, nil when its
+// text is new.
+func matchChunks(chunks []string, previous []models.Embedding) []*models.Embedding {
+	sorted := append([]models.Embedding(nil), previous...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	byHash := make(map[string][]int)
+	for i, chunk := range sorted {
+		hash := contentHash(chunk.TextChunk)
+		byHash[hash] = append(byHash[hash], i)
+	}
+
+	matched := make([]*models.Embedding, len(chunks))
+	for i, chunk := range chunks {
+		hash := contentHash(chunk)
+		if candidates := byHash[hash]; len(candidates) > 0 {
+			matched[i] = &sorted[candidates[0]]
+			byHash[hash] = candidates[1:]
+		}
+	}
+	return matched
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestMatchChunks(t *testing.T) {
+	previous := []models.Embedding{
+		{ID: "c", TextChunk: "repeated", Order: 2},
+		{ID: "a", TextChunk: "intro", Order: 0},
+		{ID: "b", TextChunk: "repeated", Order: 1},
+		{ID: "d", TextChunk: "removed", Order: 3},
+	}
+	chunks := []string{"intro", "new", "repeated", "repeated", "repeated"}
+
+	matched := matchChunks(chunks, previous)
+
+	var got []string
+	for _, chunk := range matched {
+		if chunk == nil {
+			got = append(got, "")
+			continue
+		}
+		got = append(got, chunk.ID)
+	}
+	// each previous chunk is reused once, in document order
+	if want := []string{"a", "", "b", "c", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matchChunks() = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	tests := []struct {
+		name   string
+		update map[string]*string
+		keep   []string
+		merge  bool
+		want   map[string]string
+	}{
+		{
+			name:   "merge",
+			update: map[string]*string{"owner": ptr("legal"), "draft": nil},
+			merge:  true,
+			want:   map[string]string{"chunker": "sentence", "owner": "legal", "tenant": "acme"},
+		},
+		{
+			name:   "replace keeps the recorded keys",
+			update: map[string]*string{"owner": ptr("legal")},
+			keep:   chunkingKeys,
+			want:   map[string]string{"chunker": "sentence", "owner": "legal"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := map[string]string{"chunker": "sentence", "owner": "it", "tenant": "acme", "draft": "yes"}
+			got := updateMetadata(metadata, models.FieldUpdate{Metadata: tt.update, ReplaceMetadata: !tt.merge}, tt.keep)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updateMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateMetadataOfDocument(t *testing.T) {
+	db := &fakeDatabase{
+		docs: map[string]models.Document{
+			"doc": {ID: "doc", Category: "Policies", Metadata: map[string]string{"chunker": "sentence", "owner": "it"}},
+		},
+		chunks: []models.Embedding{
+			{ID: "c1", DocumentID: "doc", Vector: []float32{1, 0}, Category: "Policies", Metadata: map[string]string{"owner": "it", "page": "2"}},
+		},
+	}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+
+	doc, err := r.UpdateMetadata(context.Background(), "doc", DocumentUpdate{FieldUpdate: models.FieldUpdate{
+		Category:        ptr("Compliance"),
+		Metadata:        map[string]*string{"owner": ptr("legal")},
+		ReplaceMetadata: true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := map[string]string{"chunker": "sentence", "owner": "legal"}; doc.Category != "Compliance" || !reflect.DeepEqual(doc.Metadata, want) {
+		t.Errorf("document = %s %v, want Compliance %v", doc.Category, doc.Metadata, want)
+	}
+	chunk := db.chunks[0]
+	if want := map[string]string{"owner": "legal", "page": "2"}; chunk.Category != "Compliance" || !reflect.DeepEqual(chunk.Metadata, want) {
+		t.Errorf("chunk = %s %v, want Compliance %v", chunk.Category, chunk.Metadata, want)
+	}
+	if !reflect.DeepEqual(chunk.Vector, []float32{1, 0}) {
+		t.Errorf("chunk vector = %v, want it unchanged", chunk.Vector)
+	}
+}
+
+func TestUpdateMissingDocument(t *testing.T) {
+	r := NewRag(nil, fakeEmbeddings{}, &fakeDatabase{docs: map[string]models.Document{}}, nil)
+
+	if _, err := r.UpdateMetadata(context.Background(), "missing", DocumentUpdate{FieldUpdate: models.FieldUpdate{Category: ptr("x")}}); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("UpdateMetadata() error = %v, want database.ErrNotFound", err)
+	}
+	if _, err := r.EnqueueUpdate(context.Background(), "missing", DocumentUpdate{Content: ptr("x")}); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("EnqueueUpdate() error = %v, want database.ErrNotFound", err)
+	}
+}
+
+func TestRecordedChunking(t *testing.T) {
+	metadata := chunkingMetadata(nil, models.Chunking{Strategy: "semantic", Size: 2000, Percentile: 7.5})
+	want := &models.Chunking{Strategy: "semantic", Size: 2000, Percentile: 7.5}
+	if got := recordedChunking(metadata); !reflect.DeepEqual(got, want) {
+		t.Errorf("recordedChunking() = %+v, want %+v", got, want)
+	}
+	if got := recordedChunking(map[string]string{}); got != nil {
+		t.Errorf("recordedChunking() = %+v, want nil", got)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
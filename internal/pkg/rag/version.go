@@ -0,0 +1,45 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/pkg/history"
+)
+
+// ErrHistoryDisabled is returned when the versions of the documents are not recorded
+var ErrHistoryDisabled = errors.New("document history disabled")
+
+// Versions returns the versions recorded for a document without their content, oldest first
+func (r *Rag) Versions(ctx context.Context, id string) ([]history.Version, error) {
+	if err := r.checkHistory(ctx, id); err != nil {
+		return nil, err
+	}
+	return r.History.List(id)
+}
+
+// DocumentVersion returns a version of a document with its content
+func (r *Rag) DocumentVersion(ctx context.Context, id string, number int64) (history.Version, error) {
+	if err := r.checkHistory(ctx, id); err != nil {
+		return history.Version{}, err
+	}
+	return r.History.Get(id, number)
+}
+
+// checkHistory fails when the versions are not recorded or the document doesn't exist
+func (r *Rag) checkHistory(ctx context.Context, id string) error {
+	if r.History == nil {
+		return ErrHistoryDisabled
+	}
+
+	doc, err := r.Database.GetDocumentInfo(ctx, id)
+	if err != nil {
+		return err
+	}
+	if doc.ID == "" {
+		return fmt.Errorf("%w: %s", database.ErrNotFound, id)
+	}
+	return nil
+}
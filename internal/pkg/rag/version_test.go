@@ -0,0 +1,49 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/database"
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/elchemista/easy_rag/internal/pkg/history"
+)
+
+func TestDocumentVersions(t *testing.T) {
+	db := &fakeDatabase{docs: map[string]models.Document{"doc": {ID: "doc", Version: 2}}}
+	r := NewRag(nil, fakeEmbeddings{}, db, nil)
+	ctx := context.Background()
+
+	if _, err := r.Versions(ctx, "doc"); !errors.Is(err, ErrHistoryDisabled) {
+		t.Errorf("Versions() error = %v, want ErrHistoryDisabled", err)
+	}
+
+	r.History, _ = history.NewStore("")
+	r.History.Save("doc", history.Version{Version: 1, CreatedAt: time.Unix(1000, 0), Content: "first"})
+	r.History.Save("doc", history.Version{Version: 2, CreatedAt: time.Unix(2000, 0), Content: "second"})
+
+	versions, err := r.Versions(ctx, "doc")
+	if err != nil {
+		t.Fatalf("Versions() error = %v", err)
+	}
+	if len(versions) != 2 || versions[1].Version != 2 || versions[1].Content != "" {
+		t.Errorf("Versions() = %+v, want versions 1 and 2 without content", versions)
+	}
+
+	version, err := r.DocumentVersion(ctx, "doc", 1)
+	if err != nil {
+		t.Fatalf("DocumentVersion() error = %v", err)
+	}
+	if version.Content != "first" {
+		t.Errorf("DocumentVersion() content = %q, want %q", version.Content, "first")
+	}
+
+	if _, err := r.DocumentVersion(ctx, "doc", 3); !errors.Is(err, history.ErrNotFound) {
+		t.Errorf("DocumentVersion() missing error = %v, want history.ErrNotFound", err)
+	}
+	if _, err := r.Versions(ctx, "missing"); !errors.Is(err, database.ErrNotFound) {
+		t.Errorf("Versions() of a missing document error = %v, want database.ErrNotFound", err)
+	}
+}
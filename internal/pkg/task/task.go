@@ -0,0 +1,410 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elchemista/easy_rag/internal/pkg/fsutil"
+)
+
+// Status describes the lifecycle stage of a task or of a single document inside it
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusFailed    Status = "failed"
+	StatusCompleted Status = "completed"
+)
+
+// ErrNotFound is returned when a task id is unknown to the tracker
+var ErrNotFound = errors.New("task not found")
+
+// DocumentProgress tracks the processing of one document of an upload task
+type DocumentProgress struct {
+	Index      int    `json:"index"`                 // Position of the document in the upload request
+	Filename   string `json:"filename"`              // Filename sent by the client
+	DocumentID string `json:"document_id,omitempty"` // Generated document ID, set once processing starts
+	Status     Status `json:"status"`                // Current status of the document
+	Step       string `json:"step,omitempty"`        // Current processing step (chunking, summarizing, ...)
+	Chunks     int    `json:"chunks"`                // Number of chunks created for the document
+	Skipped    bool   `json:"skipped,omitempty"`     // Already uploaded, DocumentID is the existing document
+	Error      string `json:"error,omitempty"`       // Error message if the document failed
+}
+
+// Task represents the state of an upload task
+type Task struct {
+	ID          string             `json:"id"`
+	Status      Status             `json:"status"`
+	Error       string             `json:"error,omitempty"`
+	Documents   []DocumentProgress `json:"documents"`
+	DocumentIDs []string           `json:"document_ids"` // IDs of the documents successfully saved
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	FinishedAt  *time.Time         `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the task reached a final status
+func (t Task) Done() bool {
+	return t.Status == StatusFailed || t.Status == StatusCompleted
+}
+
+// clone returns a deep copy of the task so callers can't mutate the tracker state
+func (t *Task) clone() Task {
+	c := *t
+	c.Documents = append([]DocumentProgress(nil), t.Documents...)
+	c.DocumentIDs = append([]string(nil), t.DocumentIDs...)
+	if t.FinishedAt != nil {
+		finished := *t.FinishedAt
+		c.FinishedAt = &finished
+	}
+	return c
+}
+
+// Tracker keeps the state of every task in memory and persists it as one JSON file per task
+type Tracker struct {
+	mu    sync.RWMutex
+	dir   string
+	tasks map[string]*Task
+
+	Retention time.Duration // How long finished tasks are kept, 0 keeps them forever
+}
+
+// NewTracker creates a tracker persisting tasks in dir, loading the tasks already stored there.
+// An empty dir keeps the tasks in memory only.
+func NewTracker(dir string) (*Tracker, error) {
+	t := &Tracker{
+		dir:   dir,
+		tasks: make(map[string]*Task),
+	}
+
+	if dir == "" {
+		return t, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create task directory: %w", err)
+	}
+
+	if err := t.load(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// load reads the persisted tasks
+func (t *Tracker) load() error {
+	files, err := filepath.Glob(filepath.Join(t.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list task files: %w", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read task file %s: %w", file, err)
+		}
+
+		var task Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			log.Printf("Skipping corrupted task file %s: %v", file, err)
+			continue
+		}
+
+		t.tasks[task.ID] = &task
+	}
+
+	return nil
+}
+
+// Create registers a new queued task with one entry per document filename
+func (t *Tracker) Create(id string, filenames []string) (Task, error) {
+	now := time.Now().UTC()
+	task := &Task{
+		ID:          id,
+		Status:      StatusQueued,
+		Documents:   make([]DocumentProgress, len(filenames)),
+		DocumentIDs: []string{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	for i, filename := range filenames {
+		task.Documents[i] = DocumentProgress{
+			Index:    i,
+			Filename: filename,
+			Status:   StatusQueued,
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, exists := t.tasks[id]; exists {
+		return Task{}, fmt.Errorf("task %s already exists", id)
+	}
+
+	if err := t.persist(task); err != nil {
+		return Task{}, err
+	}
+	t.tasks[id] = task
+
+	return task.clone(), nil
+}
+
+// Get returns a copy of the task with the given id
+func (t *Tracker) Get(id string) (Task, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	task, ok := t.tasks[id]
+	if !ok {
+		return Task{}, ErrNotFound
+	}
+	return task.clone(), nil
+}
+
+// Unfinished returns the ids of the tasks that haven't reached a final status,
+// e.g. the ones interrupted by a server restart
+func (t *Tracker) Unfinished() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var ids []string
+	for id, task := range t.tasks {
+		if !task.Done() {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Requeue puts an interrupted task back in the queued status. Documents already
+// completed keep their status so they are not processed twice.
+func (t *Tracker) Requeue(id string) error {
+	return t.update(id, func(task *Task) error {
+		task.Status = StatusQueued
+		task.Error = ""
+		task.FinishedAt = nil
+		for i := range task.Documents {
+			doc := &task.Documents[i]
+			if doc.Status != StatusCompleted {
+				doc.Status = StatusQueued
+				doc.Step = ""
+				doc.Error = ""
+			}
+		}
+		return nil
+	})
+}
+
+// Start marks the task as running
+func (t *Tracker) Start(id string) error {
+	return t.update(id, func(task *Task) error {
+		task.Status = StatusRunning
+		return nil
+	})
+}
+
+// StartDocument marks a document as running and records its generated ID
+func (t *Tracker) StartDocument(id string, index int, documentID string) error {
+	return t.updateDocument(id, index, func(doc *DocumentProgress) {
+		doc.Status = StatusRunning
+		doc.DocumentID = documentID
+	})
+}
+
+// SetStep records the processing step a document is currently in
+func (t *Tracker) SetStep(id string, index int, step string) error {
+	return t.updateDocument(id, index, func(doc *DocumentProgress) {
+		doc.Step = step
+	})
+}
+
+// SetChunks records how many chunks were created for a document
+func (t *Tracker) SetChunks(id string, index int, chunks int) error {
+	return t.updateDocument(id, index, func(doc *DocumentProgress) {
+		doc.Chunks = chunks
+	})
+}
+
+// CompleteDocument marks a document as completed and adds it to the produced document IDs.
+// documentID is the ID the document was saved with, the generated one unless it replaced
+// an existing document.
+func (t *Tracker) CompleteDocument(id string, index int, documentID string) error {
+	return t.update(id, func(task *Task) error {
+		doc, err := documentAt(task, index)
+		if err != nil {
+			return err
+		}
+		doc.Status = StatusCompleted
+		doc.Step = ""
+		doc.DocumentID = documentID
+		task.DocumentIDs = append(task.DocumentIDs, doc.DocumentID)
+		return nil
+	})
+}
+
+// SkipDocument marks a document already uploaded as the existing document with the given ID
+func (t *Tracker) SkipDocument(id string, index int, documentID string) error {
+	return t.update(id, func(task *Task) error {
+		doc, err := documentAt(task, index)
+		if err != nil {
+			return err
+		}
+		doc.Status = StatusCompleted
+		doc.Step = ""
+		doc.DocumentID = documentID
+		doc.Skipped = true
+		task.DocumentIDs = append(task.DocumentIDs, doc.DocumentID)
+		return nil
+	})
+}
+
+// FailDocument marks a document as failed with the given error
+func (t *Tracker) FailDocument(id string, index int, cause error) error {
+	return t.updateDocument(id, index, func(doc *DocumentProgress) {
+		doc.Status = StatusFailed
+		doc.Error = cause.Error()
+	})
+}
+
+// Finish sets the final status of the task: completed if every document succeeded, failed otherwise
+func (t *Tracker) Finish(id string) error {
+	return t.finish(id, func(task *Task) error {
+		failed := 0
+		for _, doc := range task.Documents {
+			if doc.Status == StatusFailed {
+				failed++
+			}
+		}
+
+		if failed > 0 {
+			finishTask(task, StatusFailed, fmt.Sprintf("%d of %d documents failed", failed, len(task.Documents)))
+		} else {
+			finishTask(task, StatusCompleted, "")
+		}
+		return nil
+	})
+}
+
+// Fail marks the whole task as failed with the given error
+func (t *Tracker) Fail(id string, cause error) error {
+	return t.finish(id, func(task *Task) error {
+		finishTask(task, StatusFailed, cause.Error())
+		return nil
+	})
+}
+
+// Prune removes the tasks finished for longer than Retention, with their files. It runs each
+// time a task finishes.
+func (t *Tracker) Prune() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.Retention <= 0 {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-t.Retention)
+	for id, task := range t.tasks {
+		if task.FinishedAt == nil || task.FinishedAt.After(cutoff) {
+			continue
+		}
+		if t.dir != "" {
+			if err := os.Remove(filepath.Join(t.dir, fileName(id))); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove task file: %w", err)
+			}
+		}
+		delete(t.tasks, id)
+	}
+
+	return nil
+}
+
+// finish applies fn, which sets the final status of the task, and prunes the expired tasks
+func (t *Tracker) finish(id string, fn func(task *Task) error) error {
+	if err := t.update(id, fn); err != nil {
+		return err
+	}
+
+	// the task just finished is kept, older ones may have expired
+	if err := t.Prune(); err != nil {
+		log.Printf("Failed to prune tasks: %v", err)
+	}
+	return nil
+}
+
+func (t *Tracker) updateDocument(id string, index int, fn func(doc *DocumentProgress)) error {
+	return t.update(id, func(task *Task) error {
+		doc, err := documentAt(task, index)
+		if err != nil {
+			return err
+		}
+		fn(doc)
+		return nil
+	})
+}
+
+// update applies fn to the task and persists the result
+func (t *Tracker) update(id string, fn func(task *Task) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	task, ok := t.tasks[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if err := fn(task); err != nil {
+		return err
+	}
+	task.UpdatedAt = time.Now().UTC()
+
+	return t.persist(task)
+}
+
+// persist writes the task to disk, replacing the previous file atomically
+func (t *Tracker) persist(task *Task) error {
+	if t.dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(filepath.Join(t.dir, fileName(task.ID)), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write task file: %w", err)
+	}
+
+	return nil
+}
+
+func documentAt(task *Task, index int) (*DocumentProgress, error) {
+	if index < 0 || index >= len(task.Documents) {
+		return nil, fmt.Errorf("task %s has no document at index %d", task.ID, index)
+	}
+	return &task.Documents[index], nil
+}
+
+func finishTask(task *Task, status Status, message string) {
+	now := time.Now().UTC()
+	task.Status = status
+	task.Error = message
+	task.UpdatedAt = now
+	task.FinishedAt = &now
+}
+
+// fileName keeps task ids from escaping the task directory
+func fileName(id string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(id) + ".json"
+}
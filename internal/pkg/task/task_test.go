@@ -0,0 +1,105 @@
+package task
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrackerPersistsTasks(t *testing.T) {
+	dir := t.TempDir()
+
+	tracker, err := NewTracker(dir)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	if _, err := tracker.Create("done", []string{"a.txt", "b.txt"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	tracker.Start("done")
+	tracker.StartDocument("done", 0, "doc-a")
+	tracker.CompleteDocument("done", 0, "doc-a")
+	tracker.StartDocument("done", 1, "doc-b")
+	tracker.FailDocument("done", 1, errors.New("summary failed"))
+	tracker.Finish("done")
+
+	if _, err := tracker.Create("running", []string{"c.txt"}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	tracker.Start("running")
+
+	reloaded, err := NewTracker(dir)
+	if err != nil {
+		t.Fatalf("NewTracker() reload error = %v", err)
+	}
+
+	done, err := reloaded.Get("done")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if done.Status != StatusFailed || done.Error != "1 of 2 documents failed" {
+		t.Errorf("done task = %s %q, want failed with partial error", done.Status, done.Error)
+	}
+	if len(done.DocumentIDs) != 1 || done.DocumentIDs[0] != "doc-a" {
+		t.Errorf("done task document ids = %v, want [doc-a]", done.DocumentIDs)
+	}
+	if done.Documents[1].Error != "summary failed" {
+		t.Errorf("document error = %q, want %q", done.Documents[1].Error, "summary failed")
+	}
+
+	unfinished := reloaded.Unfinished()
+	if len(unfinished) != 1 || unfinished[0] != "running" {
+		t.Fatalf("Unfinished() = %v, want [running]", unfinished)
+	}
+	if err := reloaded.Requeue("running"); err != nil {
+		t.Fatalf("Requeue() error = %v", err)
+	}
+	running, _ := reloaded.Get("running")
+	if running.Status != StatusQueued {
+		t.Errorf("requeued task status = %s, want queued", running.Status)
+	}
+
+	if _, err := reloaded.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() missing error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTrackerPrunesFinishedTasks(t *testing.T) {
+	dir := t.TempDir()
+
+	tracker, err := NewTracker(dir)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	for _, id := range []string{"old", "recent", "running"} {
+		if _, err := tracker.Create(id, []string{id + ".txt"}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	tracker.Fail("old", errors.New("failed"))
+	finished := time.Now().Add(-2 * time.Hour)
+	tracker.tasks["old"].FinishedAt = &finished
+	tracker.persist(tracker.tasks["old"])
+	tracker.Start("running")
+
+	// finishing a task prunes the expired ones
+	tracker.Retention = time.Hour
+	if err := tracker.Finish("recent"); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	if _, err := tracker.Get("old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(old) error = %v, want ErrNotFound", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.json")); !os.IsNotExist(err) {
+		t.Errorf("old task file still exists: %v", err)
+	}
+	for _, id := range []string{"recent", "running"} {
+		if _, err := tracker.Get(id); err != nil {
+			t.Errorf("Get(%s) error = %v, want the task kept", id, err)
+		}
+	}
+}
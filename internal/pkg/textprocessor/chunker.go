@@ -0,0 +1,361 @@
+package textprocessor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/elchemista/easy_rag/internal/embeddings"
+	"github.com/elchemista/easy_rag/internal/models"
+	"github.com/jonathanhecl/chunker"
+)
+
+// Chunking strategies
+const (
+	StrategySentence  = "sentence"  // Sentences packed up to the size
+	StrategyFixed     = "fixed"     // Windows of a fixed number of characters
+	StrategyRecursive = "recursive" // Split on paragraphs, then lines, sentences and words until the parts fit
+	StrategyMarkdown  = "markdown"  // One chunk per Markdown section, large sections split recursively
+	StrategyToken     = "token"     // Words packed up to a number of tokens of the embedding model
+	StrategySemantic  = "semantic"  // Cut where the embedding similarity of adjacent sentences drops
+)
+
+// strategies lists the chunking strategies for error messages
+var strategies = []string{StrategySentence, StrategyFixed, StrategyRecursive, StrategyMarkdown, StrategyToken, StrategySemantic}
+
+// Default chunk sizes, in characters or in tokens for StrategyToken
+const (
+	DefaultSentenceSize = 5000 // too slow otherwise
+	DefaultChunkSize    = 2000
+	DefaultTokenSize    = 512
+)
+
+// Chunker splits a text into chunks
+type Chunker interface {
+	Chunk(ctx context.Context, text string) ([]string, error)
+}
+
+// Resolve sets the default strategy and size of the options and validates them
+func Resolve(opts models.Chunking) (models.Chunking, error) {
+	if opts.Strategy == "" {
+		opts.Strategy = StrategySentence
+	}
+	if opts.Size == 0 {
+		switch opts.Strategy {
+		case StrategySentence:
+			opts.Size = DefaultSentenceSize
+		case StrategyToken:
+			opts.Size = DefaultTokenSize
+		default:
+			opts.Size = DefaultChunkSize
+		}
+	}
+
+	if opts.Strategy == StrategySemantic && opts.Percentile == 0 {
+		opts.Percentile = DefaultPercentile
+	}
+
+	switch opts.Strategy {
+	case StrategySentence, StrategyFixed, StrategyRecursive, StrategyMarkdown, StrategyToken, StrategySemantic:
+	default:
+		return opts, fmt.Errorf("unknown chunking strategy %q, expected one of %s", opts.Strategy, strings.Join(strategies, ", "))
+	}
+	if opts.Size < 0 || opts.Overlap < 0 {
+		return opts, fmt.Errorf("chunk size and overlap must not be negative")
+	}
+	if opts.Overlap >= opts.Size {
+		return opts, fmt.Errorf("chunk overlap %d must be smaller than the chunk size %d", opts.Overlap, opts.Size)
+	}
+	if opts.Strategy == StrategySemantic && opts.Overlap > 0 {
+		return opts, fmt.Errorf("the semantic chunking strategy does not support overlap")
+	}
+	if opts.Strategy != StrategySemantic && opts.Percentile != 0 {
+		return opts, fmt.Errorf("the percentile only applies to the semantic chunking strategy")
+	}
+	if opts.Percentile < 0 || opts.Percentile >= 100 {
+		return opts, fmt.Errorf("chunk percentile %v must be between 0 and 100", opts.Percentile)
+	}
+
+	return opts, nil
+}
+
+// Limits are the hard limits of every chunk, whatever the strategy
+type Limits struct {
+	Tokenizer Tokenizer // Counts the tokens of the token strategy and of MaxTokens, Words if nil
+	MaxTokens int       // Context size of the embedding model, 0 is unlimited
+	MaxBytes  int       // Largest chunk stored, 0 is unlimited
+}
+
+// NewChunker returns the chunker of the options, with the defaults of Resolve, whose
+// chunks are split again when they exceed the limits. The embedding service is only used
+// by the semantic strategy.
+func NewChunker(opts models.Chunking, limits Limits, service embeddings.EmbeddingsService) (Chunker, error) {
+	opts, err := Resolve(opts)
+	if err != nil {
+		return nil, err
+	}
+	if limits.Tokenizer == nil {
+		limits.Tokenizer = Words{}
+	}
+
+	var chunker Chunker
+	switch opts.Strategy {
+	case StrategyFixed:
+		chunker = Fixed{Size: opts.Size, Overlap: opts.Overlap}
+	case StrategyRecursive:
+		chunker = Recursive{Size: opts.Size, Overlap: opts.Overlap}
+	case StrategyMarkdown:
+		chunker = Markdown{Size: opts.Size, Overlap: opts.Overlap}
+	case StrategyToken:
+		chunker = Tokens{Size: opts.Size, Overlap: opts.Overlap, Tokenizer: limits.Tokenizer}
+	case StrategySemantic:
+		if service == nil {
+			return nil, fmt.Errorf("the semantic chunking strategy needs an embedding service")
+		}
+		chunker = Semantic{Embeddings: service, Size: opts.Size, Percentile: opts.Percentile, Buffer: 1}
+	default:
+		chunker = Sentences{Size: opts.Size, Overlap: opts.Overlap}
+	}
+
+	if limits.MaxTokens <= 0 && limits.MaxBytes <= 0 {
+		return chunker, nil
+	}
+	return Limited{Chunker: chunker, Tokenizer: limits.Tokenizer, MaxTokens: limits.MaxTokens, MaxBytes: limits.MaxBytes}, nil
+}
+
+// Sentences packs whole sentences into chunks of up to Size characters, the last
+// sentences of a chunk up to Overlap characters start the next one. A sentence larger
+// than Size is split on its words.
+type Sentences struct {
+	Size    int
+	Overlap int
+}
+
+func (s Sentences) Chunk(ctx context.Context, text string) ([]string, error) {
+	return s.split(text), nil
+}
+
+// split packs the sentences of the text
+func (s Sentences) split(text string) []string {
+	return packSentences(chunker.ChunkSentences(text), s.Size, s.Overlap)
+}
+
+// packSentences packs the sentences into chunks of up to size characters, splitting the
+// ones larger than size on their words
+func packSentences(sentences []string, size, overlap int) []string {
+	fits := func(part string) bool { return runeCount(part) <= size }
+
+	var parts []string
+	for _, sentence := range sentences {
+		if fits(sentence) {
+			parts = append(parts, sentence)
+			continue
+		}
+		for _, part := range splitToFit(sentence, separators, fits) {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	return pack(parts, " ", size, overlap, runeCount)
+}
+
+// Fixed cuts the text into windows of Size characters, each one starting Overlap
+// characters before the end of the previous one. Words may be cut.
+type Fixed struct {
+	Size    int
+	Overlap int
+}
+
+func (f Fixed) Chunk(ctx context.Context, text string) ([]string, error) {
+	return windows([]rune(text), f.Size, f.Overlap), nil
+}
+
+// Tokens packs words into chunks of up to Size tokens counted by the Tokenizer, the last
+// words of a chunk up to Overlap tokens start the next one. The spacing between words is
+// kept, a word larger than Size is cut.
+type Tokens struct {
+	Size      int
+	Overlap   int
+	Tokenizer Tokenizer // Words if nil
+}
+
+func (t Tokens) Chunk(ctx context.Context, text string) ([]string, error) {
+	tokenizer := t.Tokenizer
+	if tokenizer == nil {
+		tokenizer = Words{}
+	}
+	fits := func(part string) bool { return tokenizer.Count(part) <= t.Size }
+
+	var parts []string
+	for _, word := range words(text) {
+		if fits(word) {
+			parts = append(parts, word)
+			continue
+		}
+		parts = append(parts, splitToFit(word, nil, fits)...)
+	}
+
+	return pack(parts, "", t.Size, t.Overlap, tokenizer.Count), nil
+}
+
+// words splits the text before each run of non-space characters, each part keeps the
+// spaces that follow it
+func words(text string) []string {
+	spans := tokenSpans(text)
+	parts := make([]string, len(spans))
+	for i, span := range spans {
+		end := len(text)
+		if i+1 < len(spans) {
+			end = spans[i+1][0]
+		}
+		parts[i] = text[span[0]:end]
+	}
+	return parts
+}
+
+// Limited splits again the chunks of a chunker larger than MaxTokens tokens or MaxBytes
+// bytes, on paragraphs, lines, sentences and words like Recursive
+type Limited struct {
+	Chunker   Chunker
+	Tokenizer Tokenizer
+	MaxTokens int // 0 is unlimited
+	MaxBytes  int // 0 is unlimited
+}
+
+func (l Limited) Chunk(ctx context.Context, text string) ([]string, error) {
+	split, err := l.Chunker.Chunk(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []string
+	for _, chunk := range split {
+		if l.fits(chunk) {
+			chunks = append(chunks, chunk)
+			continue
+		}
+
+		current := ""
+		for _, part := range splitToFit(chunk, separators, l.fits) {
+			if current != "" && !l.fits(current+part) {
+				chunks = appendChunk(chunks, current)
+				current = ""
+			}
+			current += part
+		}
+		chunks = appendChunk(chunks, current)
+	}
+	return chunks, nil
+}
+
+func (l Limited) fits(chunk string) bool {
+	if l.MaxBytes > 0 && len(chunk) > l.MaxBytes {
+		return false
+	}
+	return l.MaxTokens <= 0 || l.Tokenizer.Count(chunk) <= l.MaxTokens
+}
+
+// tokenSpans returns the byte offsets of the runs of non-space characters
+func tokenSpans(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i, r := range text {
+		if isSpace(r) {
+			if start >= 0 {
+				spans = append(spans, [2]int{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f' || r == 0x85 || r == 0xA0
+}
+
+// windows cuts the runes into windows of size, each one starting overlap runes before
+// the end of the previous one
+func windows(runes []rune, size, overlap int) []string {
+	var chunks []string
+	for start := 0; start < len(runes); start += size - overlap {
+		end := min(start+size, len(runes))
+		chunks = appendChunk(chunks, string(runes[start:end]))
+		if end == len(runes) {
+			break
+		}
+	}
+	return chunks
+}
+
+// pack joins consecutive parts into chunks of up to size, measured by length. When a
+// chunk is full, its last parts up to overlap start the next one. A part larger than
+// size becomes a chunk of its own. Each part is measured once.
+func pack(parts []string, joiner string, size, overlap int, length func(string) int) []string {
+	var chunks []string
+	var current []string
+	var lengths []int // length of each part of current
+	total := 0        // length of the parts of current once joined
+	sep := length(joiner)
+
+	for _, part := range parts {
+		partLength := length(part)
+		if len(current) > 0 && total+sep+partLength > size {
+			chunks = appendChunk(chunks, strings.Join(current, joiner))
+
+			keep, kept := len(current), 0
+			for keep > 0 {
+				next := lengths[keep-1]
+				if keep < len(current) {
+					next += sep
+				}
+				if kept+next > overlap {
+					break
+				}
+				kept += next
+				keep--
+			}
+			current, lengths, total = current[keep:], lengths[keep:], kept
+
+			// the overlap must leave room for the part
+			for len(current) > 0 && total+sep+partLength > size {
+				total -= lengths[0]
+				if len(current) > 1 {
+					total -= sep
+				}
+				current, lengths = current[1:], lengths[1:]
+			}
+		}
+		if len(current) > 0 {
+			total += sep
+		}
+		total += partLength
+		current = append(current, part)
+		lengths = append(lengths, partLength)
+	}
+	if len(current) > 0 {
+		chunks = appendChunk(chunks, strings.Join(current, joiner))
+	}
+
+	return chunks
+}
+
+// appendChunk adds the chunk unless it is blank
+func appendChunk(chunks []string, chunk string) []string {
+	if chunk = strings.TrimSpace(chunk); chunk == "" {
+		return chunks
+	}
+	return append(chunks, chunk)
+}
+
+func runeCount(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,211 @@
+package textprocessor
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		opts    models.Chunking
+		want    models.Chunking
+		wantErr bool
+	}{
+		{models.Chunking{}, models.Chunking{Strategy: StrategySentence, Size: DefaultSentenceSize}, false},
+		{models.Chunking{Strategy: StrategyToken}, models.Chunking{Strategy: StrategyToken, Size: DefaultTokenSize}, false},
+		{models.Chunking{Strategy: StrategyFixed, Overlap: 100}, models.Chunking{Strategy: StrategyFixed, Size: DefaultChunkSize, Overlap: 100}, false},
+		{models.Chunking{Strategy: "pages"}, models.Chunking{}, true},
+		{models.Chunking{Strategy: StrategyFixed, Size: 100, Overlap: 100}, models.Chunking{}, true},
+		{models.Chunking{Strategy: StrategyFixed, Size: -1}, models.Chunking{}, true},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(tt.opts)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Resolve(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Resolve(%+v) = %+v, want %+v", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestSentences(t *testing.T) {
+	text := "One is here. Two is here. Three is here. Four is here."
+
+	got := chunk(t, Sentences{Size: 30}, text)
+	want := []string{"One is here. Two is here.", "Three is here. Four is here."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() = %q, want %q", got, want)
+	}
+
+	got = chunk(t, Sentences{Size: 30, Overlap: 15}, text)
+	want = []string{"One is here. Two is here.", "Two is here. Three is here.", "Three is here. Four is here."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() with overlap = %q, want %q", got, want)
+	}
+}
+
+func TestPack(t *testing.T) {
+	parts := []string{"aa", "bb", "cc", "dd", "ee"}
+
+	calls := 0
+	length := func(s string) int {
+		calls++
+		return runeCount(s)
+	}
+	got := pack(parts, " ", 8, 2, length)
+	want := []string{"aa bb cc", "cc dd ee"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pack() = %q, want %q", got, want)
+	}
+	// each part and the joiner are measured once, whatever the number of parts
+	if calls != len(parts)+1 {
+		t.Errorf("length called %d times, want %d", calls, len(parts)+1)
+	}
+}
+
+func TestFixed(t *testing.T) {
+	got := chunk(t, Fixed{Size: 4, Overlap: 1}, "abcdefghij")
+	want := []string{"abcd", "defg", "ghij"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() = %q, want %q", got, want)
+	}
+
+	// sizes are in characters, not bytes
+	if got := chunk(t, Fixed{Size: 2}, "ééé"); !reflect.DeepEqual(got, []string{"éé", "é"}) {
+		t.Errorf("Chunk() = %q, want runes kept whole", got)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	got := chunk(t, Tokens{Size: 3, Overlap: 1}, "one two\nthree four  five six")
+	want := []string{"one two\nthree", "three four  five", "five six"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() = %q, want %q", got, want)
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	text := "First paragraph is short.\n\nSecond paragraph has two sentences. It is longer than the size.\n\nThird."
+
+	got := chunk(t, Recursive{Size: 40}, text)
+	want := []string{
+		"First paragraph is short.",
+		"Second paragraph has two sentences.",
+		"It is longer than the size.\n\nThird.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() = %q, want %q", got, want)
+	}
+	for _, chunk := range got {
+		if runeCount(chunk) > 40 {
+			t.Errorf("chunk %q is larger than the size", chunk)
+		}
+	}
+
+	// a word longer than the size is cut
+	if got := chunk(t, Recursive{Size: 4}, "abcdefgh ij"); !reflect.DeepEqual(got, []string{"abcd", "efgh", "ij"}) {
+		t.Errorf("Chunk() = %q", got)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	text := "# Guide\n\n## Install\n\nRun the installer.\n\n```sh\n# not a heading\n```\n\n## Usage\n\nCall it.\n"
+
+	got := chunk(t, Markdown{Size: 1000}, text)
+	want := []string{
+		"# Guide\n\n## Install\n\nRun the installer.\n\n```sh\n# not a heading\n```",
+		"## Usage\n\nCall it.",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() = %q, want %q", got, want)
+	}
+
+	// a large section is split, never merged with the next one
+	got = chunk(t, Markdown{Size: 20}, "# A\n\n"+strings.Repeat("word ", 8)+"\n# B\n\nEnd.")
+	if len(got) < 3 || got[len(got)-1] != "# B\n\nEnd." {
+		t.Errorf("Chunk() = %q, want the large section split and B alone", got)
+	}
+}
+
+func TestNewChunker(t *testing.T) {
+	chunker, err := NewChunker(models.Chunking{Strategy: StrategyRecursive, Size: 100}, Limits{}, nil)
+	if err != nil {
+		t.Fatalf("NewChunker() error = %v", err)
+	}
+	if _, ok := chunker.(Recursive); !ok {
+		t.Errorf("NewChunker() = %T, want Recursive", chunker)
+	}
+
+	if _, err := NewChunker(models.Chunking{Strategy: "unknown"}, Limits{}, nil); err == nil {
+		t.Error("NewChunker() with an unknown strategy returned no error")
+	}
+}
+
+func TestSentencesSplitsLongSentences(t *testing.T) {
+	long := strings.Repeat("word ", 20) + "end."
+	got := chunk(t, Sentences{Size: 30}, "Short one. "+long)
+
+	if len(got) < 4 || got[0] != "Short one. word word word word" {
+		t.Errorf("Chunk() = %q, want the long sentence split on its words", got)
+	}
+	for _, chunk := range got {
+		if runeCount(chunk) > 30 {
+			t.Errorf("chunk %q is larger than the size", chunk)
+		}
+	}
+}
+
+func TestTokensWithTokenizer(t *testing.T) {
+	tokenizer := Estimate{CharsPerToken: 4}
+	got := chunk(t, Tokens{Size: 5, Tokenizer: tokenizer}, "embedding models tokenize text https://example.com/a/very/long/path")
+
+	for _, chunk := range got {
+		if n := tokenizer.Count(chunk); n > 5 {
+			t.Errorf("chunk %q has %d tokens, want at most 5", chunk, n)
+		}
+	}
+	if got[0] != "embedding models" {
+		t.Errorf("Chunk()[0] = %q, want %q", got[0], "embedding models")
+	}
+}
+
+func TestLimited(t *testing.T) {
+	// the fixed chunk has 10 tokens, the model takes 4
+	chunker, err := NewChunker(models.Chunking{Strategy: StrategyFixed, Size: 1000}, Limits{MaxTokens: 4}, nil)
+	if err != nil {
+		t.Fatalf("NewChunker() error = %v", err)
+	}
+	got := chunk(t, chunker, "one two three four five six seven eight nine ten")
+	want := []string{"one two three four", "five six seven eight", "nine ten"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() = %q, want %q", got, want)
+	}
+
+	// no chunk exceeds the storage limit, multibyte characters included
+	const maxBytes = 65535
+	chunker, err = NewChunker(models.Chunking{Strategy: StrategySentence, Size: 100000}, Limits{MaxBytes: maxBytes}, nil)
+	if err != nil {
+		t.Fatalf("NewChunker() error = %v", err)
+	}
+	for _, chunk := range chunk(t, chunker, strings.Repeat("é", 50000)) {
+		if len(chunk) > maxBytes {
+			t.Errorf("chunk of %d bytes, want at most %d", len(chunk), maxBytes)
+		}
+	}
+}
+
+// chunk runs the chunker, failing the test on error
+func chunk(t *testing.T, chunker Chunker, text string) []string {
+	t.Helper()
+	chunks, err := chunker.Chunk(context.Background(), text)
+	if err != nil {
+		t.Fatalf("Chunk() error = %v", err)
+	}
+	return chunks
+}
@@ -0,0 +1,118 @@
+package textprocessor
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// separators are tried in order by Recursive, from paragraphs to words
+var separators = []string{"\n\n", "\n", ". ", "? ", "! ", "; ", ", ", " "}
+
+// Recursive splits the text on paragraphs, then on lines, sentences and words until the
+// parts fit in Size characters, and packs the parts back into chunks. The last parts of a
+// chunk up to Overlap characters start the next one.
+type Recursive struct {
+	Size    int
+	Overlap int
+}
+
+func (r Recursive) Chunk(ctx context.Context, text string) ([]string, error) {
+	return r.split(text), nil
+}
+
+// split splits the text into parts that fit and packs them
+func (r Recursive) split(text string) []string {
+	fits := func(part string) bool { return runeCount(part) <= r.Size }
+	return pack(splitToFit(text, separators, fits), "", r.Size, r.Overlap, runeCount)
+}
+
+// splitToFit splits the text on the first separator it contains, and the parts that still
+// do not fit on the next separators. Parts without separator left are cut at the longest
+// prefix that fits. The separators are kept, joining the parts gives the text back.
+func splitToFit(text string, separators []string, fits func(string) bool) []string {
+	if fits(text) {
+		return []string{text}
+	}
+
+	for i, separator := range separators {
+		if !strings.Contains(text, separator) {
+			continue
+		}
+
+		var parts []string
+		for _, part := range strings.SplitAfter(text, separator) {
+			if part != "" {
+				parts = append(parts, splitToFit(part, separators[i+1:], fits)...)
+			}
+		}
+		return parts
+	}
+
+	var parts []string
+	for runes := []rune(text); len(runes) > 0; {
+		n := longestPrefix(runes, fits)
+		parts = append(parts, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return parts
+}
+
+// longestPrefix returns the length of the longest prefix of the runes that fits, at least 1
+func longestPrefix(runes []rune, fits func(string) bool) int {
+	low, high := 1, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if fits(string(runes[:mid])) {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return low
+}
+
+// markdownHeading matches ATX headings: "# Title" to "###### Title"
+var markdownHeading = regexp.MustCompile(`^#{1,6}\s+\S`)
+
+// Markdown makes one chunk per Markdown section, so a chunk never mixes two sections.
+// Sections larger than Size characters are split like Recursive, a heading without text
+// is kept with the section that follows it.
+type Markdown struct {
+	Size    int
+	Overlap int
+}
+
+func (m Markdown) Chunk(ctx context.Context, text string) ([]string, error) {
+	recursive := Recursive{Size: m.Size, Overlap: m.Overlap}
+
+	var chunks []string
+	var section strings.Builder
+	hasText := false
+	inCode := false
+
+	flush := func() {
+		chunks = append(chunks, recursive.split(section.String())...)
+		section.Reset()
+		hasText = false
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+		}
+
+		if markdownHeading.MatchString(line) && !inCode {
+			if hasText {
+				flush()
+			}
+		} else if trimmed != "" {
+			hasText = true
+		}
+		section.WriteString(line)
+	}
+	flush()
+
+	return chunks, nil
+}
@@ -0,0 +1,97 @@
+package textprocessor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/elchemista/easy_rag/internal/embeddings"
+	"github.com/jonathanhecl/chunker"
+)
+
+// DefaultPercentile is the similarity percentile below which the semantic chunker cuts
+const DefaultPercentile = 5
+
+// Semantic cuts the text where the topic shifts. Each sentence is embedded with its
+// Buffer neighbours on each side, and a chunk ends between two adjacent sentences whose
+// similarity is below the Percentile-th percentile of all the adjacent similarities of
+// the text. Chunks larger than Size characters are split like Sentences.
+// It embeds every sentence, in batches of the embedding service.
+type Semantic struct {
+	Embeddings embeddings.EmbeddingsService
+	Size       int
+	Percentile float64
+	Buffer     int
+}
+
+func (s Semantic) Chunk(ctx context.Context, text string) ([]string, error) {
+	var sentences []string
+	for _, sentence := range chunker.ChunkSentences(text) {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+
+	// too few sentences to compare
+	if len(sentences) < 3 {
+		return packSentences(sentences, s.Size, 0), nil
+	}
+
+	contexts := make([]string, len(sentences))
+	for i := range sentences {
+		contexts[i] = strings.Join(sentences[max(0, i-s.Buffer):min(len(sentences), i+s.Buffer+1)], " ")
+	}
+	vectors, err := s.Embeddings.VectorizeBatch(ctx, contexts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed the sentences: %w", err)
+	}
+	if len(vectors) != len(sentences) {
+		return nil, fmt.Errorf("failed to embed the sentences: expected %d embeddings, got %d", len(sentences), len(vectors))
+	}
+
+	similarities := make([]float64, len(sentences)-1)
+	for i := range similarities {
+		similarities[i] = cosine(vectors[i], vectors[i+1])
+	}
+	threshold := percentile(similarities, s.Percentile)
+
+	var chunks []string
+	start := 0
+	for i, similarity := range similarities {
+		if similarity < threshold {
+			chunks = append(chunks, packSentences(sentences[start:i+1], s.Size, 0)...)
+			start = i + 1
+		}
+	}
+	chunks = append(chunks, packSentences(sentences[start:], s.Size, 0)...)
+
+	return chunks, nil
+}
+
+// cosine returns the cosine similarity of two vectors, 0 if one of them is null
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// percentile returns the p-th percentile (0 to 100) of the values, interpolated between
+// the closest ranks
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	low := int(math.Floor(rank))
+	high := int(math.Ceil(rank))
+	return sorted[low] + (sorted[high]-sorted[low])*(rank-float64(low))
+}
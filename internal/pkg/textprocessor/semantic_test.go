@@ -0,0 +1,106 @@
+package textprocessor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/elchemista/easy_rag/internal/models"
+)
+
+// topicEmbeddings embeds a text by counting the words of two topics
+type topicEmbeddings struct {
+	err error
+}
+
+func (f topicEmbeddings) Vectorize(ctx context.Context, text string) ([][]float32, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	text = strings.ToLower(text)
+	return [][]float32{{float32(strings.Count(text, "cat")), float32(strings.Count(text, "tax"))}}, nil
+}
+
+func (f topicEmbeddings) VectorizeBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	for _, text := range texts {
+		vector, err := f.Vectorize(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vector...)
+	}
+	return vectors, nil
+}
+
+func (topicEmbeddings) GetModel() string { return "topics" }
+
+func TestSemantic(t *testing.T) {
+	text := "Cats purr. Cats sleep. Cats hunt. Taxes are due. Taxes are high. Taxes rise."
+
+	chunker, err := NewChunker(models.Chunking{Strategy: StrategySemantic}, Limits{}, topicEmbeddings{})
+	if err != nil {
+		t.Fatalf("NewChunker() error = %v", err)
+	}
+	got := chunk(t, chunker, text)
+	want := []string{"Cats purr. Cats sleep. Cats hunt.", "Taxes are due. Taxes are high. Taxes rise."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Chunk() = %q, want %q", got, want)
+	}
+
+	// a topic larger than the size is split too
+	got = chunk(t, Semantic{Embeddings: topicEmbeddings{}, Size: 25, Percentile: DefaultPercentile, Buffer: 1}, text)
+	if len(got) < 4 || !strings.HasPrefix(got[len(got)-1], "Taxes") {
+		t.Errorf("Chunk() = %q, want the topics split to fit the size", got)
+	}
+
+	_, err = Semantic{Embeddings: topicEmbeddings{err: errors.New("model offline")}, Size: 100}.Chunk(context.Background(), text)
+	if err == nil || !strings.Contains(err.Error(), "model offline") {
+		t.Errorf("Chunk() error = %v, want the embedding error", err)
+	}
+
+	if _, err := NewChunker(models.Chunking{Strategy: StrategySemantic}, Limits{}, nil); err == nil {
+		t.Error("NewChunker() without embedding service returned no error")
+	}
+}
+
+func TestResolveSemantic(t *testing.T) {
+	got, err := Resolve(models.Chunking{Strategy: StrategySemantic})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if got.Percentile != DefaultPercentile || got.Size != DefaultChunkSize {
+		t.Errorf("Resolve() = %+v, want the semantic defaults", got)
+	}
+
+	for _, opts := range []models.Chunking{
+		{Strategy: StrategySemantic, Overlap: 10},
+		{Strategy: StrategySemantic, Percentile: 100},
+		{Strategy: StrategyFixed, Percentile: 10},
+	} {
+		if _, err := Resolve(opts); err == nil {
+			t.Errorf("Resolve(%+v) returned no error", opts)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{0.9, 0.1, 0.5, 0.3, 0.7}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 0.1},
+		{50, 0.5},
+		{10, 0.18},
+		{100, 0.9},
+	}
+	for _, tt := range tests {
+		if got := percentile(values, tt.p); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
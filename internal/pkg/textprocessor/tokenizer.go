@@ -0,0 +1,104 @@
+package textprocessor
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer counts the tokens of a text
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// Words counts the runs of non-space characters
+type Words struct{}
+
+func (Words) Count(text string) int {
+	return len(tokenSpans(text))
+}
+
+// Estimate approximates a subword (BPE, WordPiece, SentencePiece) tokenizer without its
+// vocabulary: a run of letters and digits counts one token per CharsPerToken characters,
+// rounded up, each punctuation or symbol character and each character of the scripts
+// written without spaces (Han, Kana, Hangul, Thai) counts one token.
+// The estimate errs on the high side for English prose.
+type Estimate struct {
+	CharsPerToken float64
+}
+
+func (e Estimate) Count(text string) int {
+	tokens := 0
+	word := 0
+	flush := func() {
+		if word > 0 {
+			tokens += int(math.Ceil(float64(word) / e.CharsPerToken))
+			word = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai):
+			flush()
+			tokens++
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			word++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// modelTokenizer is the tokenizer approximation and context size of a family of models
+type modelTokenizer struct {
+	prefix    string // Lowercase prefix of the model name, without the Ollama namespace and tag
+	tokenizer Tokenizer
+	maxTokens int
+}
+
+// models are matched in order, the first matching prefix wins
+var modelTokenizers = []modelTokenizer{
+	// OpenAI, cl100k_base
+	{"text-embedding-3", Estimate{CharsPerToken: 4}, 8191},
+	{"text-embedding-ada-002", Estimate{CharsPerToken: 4}, 8191},
+	// XLM-RoBERTa SentencePiece
+	{"bge-m3", Estimate{CharsPerToken: 3.5}, 8192},
+	{"multilingual-e5", Estimate{CharsPerToken: 3.5}, 512},
+	{"paraphrase-multilingual", Estimate{CharsPerToken: 3.5}, 128},
+	// BERT WordPiece
+	{"nomic-embed-text", Estimate{CharsPerToken: 3.5}, 8192},
+	{"mxbai-embed-large", Estimate{CharsPerToken: 3.5}, 512},
+	{"snowflake-arctic-embed", Estimate{CharsPerToken: 3.5}, 512},
+	{"bge-", Estimate{CharsPerToken: 3.5}, 512},
+	{"e5-", Estimate{CharsPerToken: 3.5}, 512},
+	{"all-minilm", Estimate{CharsPerToken: 3.5}, 256},
+	{"all-mpnet", Estimate{CharsPerToken: 3.5}, 384},
+}
+
+// defaultTokenizer is used for unknown models, its estimate is on the safe side
+var defaultTokenizer = Estimate{CharsPerToken: 3}
+
+// ModelTokenizer returns the tokenizer approximation of an embedding model and its context
+// size in tokens, 0 when it is unknown. "nomic-embed-text:latest", "library/bge-m3" and
+// "BAAI/bge-m3" match their family.
+func ModelTokenizer(model string) (Tokenizer, int) {
+	name := strings.ToLower(model)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name, _, _ = strings.Cut(name, ":")
+
+	for _, m := range modelTokenizers {
+		if strings.HasPrefix(name, m.prefix) {
+			return m.tokenizer, m.maxTokens
+		}
+	}
+	return defaultTokenizer, 0
+}
@@ -0,0 +1,44 @@
+package textprocessor
+
+import "testing"
+
+func TestEstimate(t *testing.T) {
+	tokenizer := Estimate{CharsPerToken: 4}
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 4},        // 2 + 2
+		{"hi, there!", 5},         // 1 + comma + 2 + !
+		{"日本語のテキスト", 8},           // one per character
+		{"naïve café", 3},         // 2 + 1, accents are part of the words
+		{"  spaced \n\t out ", 3}, // 2 + 1, whitespace is free
+	}
+	for _, tt := range tests {
+		if got := tokenizer.Count(tt.text); got != tt.want {
+			t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestModelTokenizer(t *testing.T) {
+	tests := []struct {
+		model     string
+		maxTokens int
+	}{
+		{"text-embedding-3-small", 8191},
+		{"bge-m3", 8192},
+		{"BAAI/bge-m3", 8192},
+		{"nomic-embed-text:latest", 8192},
+		{"all-minilm:l6-v2", 256},
+		{"bge-large-en-v1.5", 512},
+		{"my-custom-model", 0},
+	}
+	for _, tt := range tests {
+		tokenizer, maxTokens := ModelTokenizer(tt.model)
+		if tokenizer == nil || maxTokens != tt.maxTokens {
+			t.Errorf("ModelTokenizer(%q) = %v, %d, want max tokens %d", tt.model, tokenizer, maxTokens, tt.maxTokens)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// HTTPReranker implements Reranker for /rerank endpoints of cross-encoder servers:
+// Hugging Face Text Embeddings Inference (TEI) and Jina/Cohere-compatible APIs
+type HTTPReranker struct {
+	Endpoint string // Base URL or full /rerank URL
+	APIKey   string
+	Model    string // Required by Jina/Cohere-compatible APIs, ignored by TEI
+}
+
+func NewHTTPReranker(endpoint string, apiKey string, model string) *HTTPReranker {
+	return &HTTPReranker{
+		Endpoint: endpoint,
+		APIKey:   apiKey,
+		Model:    model,
+	}
+}
+
+// rerankResult is one scored document, "score" for TEI and "relevance_score" for Jina/Cohere
+type rerankResult struct {
+	Index          int      `json:"index"`
+	Score          *float32 `json:"score"`
+	RelevanceScore *float32 `json:"relevance_score"`
+}
+
+// Rerank sends the query and the documents in a single request
+func (h *HTTPReranker) Rerank(ctx context.Context, query string, documents []string) ([]float32, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	// TEI reads "texts", Jina/Cohere read "documents"
+	payload := map[string]interface{}{
+		"query":     query,
+		"texts":     documents,
+		"documents": documents,
+		"top_n":     len(documents),
+	}
+	if h.Model != "" {
+		payload["model"] = h.Model
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rerank request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url(), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rerank request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make rerank request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rerank response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank returned non-200 response (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	results, err := parseRerankResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]float32, len(documents))
+	seen := make([]bool, len(documents))
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(documents) {
+			return nil, fmt.Errorf("rerank returned unknown document index %d", result.Index)
+		}
+
+		switch {
+		case result.Score != nil:
+			scores[result.Index] = *result.Score
+		case result.RelevanceScore != nil:
+			scores[result.Index] = *result.RelevanceScore
+		default:
+			return nil, fmt.Errorf("rerank returned no score for document %d", result.Index)
+		}
+		seen[result.Index] = true
+	}
+
+	for i, ok := range seen {
+		if !ok {
+			return nil, fmt.Errorf("rerank returned no score for document %d", i)
+		}
+	}
+
+	return scores, nil
+}
+
+// parseRerankResponse reads a TEI array of results or a Jina/Cohere {"results": [...]} object
+func parseRerankResponse(body []byte) ([]rerankResult, error) {
+	var results []rerankResult
+	if err := json.Unmarshal(body, &results); err == nil {
+		return results, nil
+	}
+
+	var response struct {
+		Results []rerankResult `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rerank response: %w", err)
+	}
+	return response.Results, nil
+}
+
+// url returns the rerank URL, accepting either a base URL or the full path
+func (h *HTTPReranker) url() string {
+	endpoint := strings.TrimRight(h.Endpoint, "/")
+	if strings.HasSuffix(endpoint, "/rerank") {
+		return endpoint
+	}
+	return endpoint + "/rerank"
+}
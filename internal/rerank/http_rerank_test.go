@@ -0,0 +1,62 @@
+package rerank
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+var _ Reranker = (*HTTPReranker)(nil)
+
+func TestHTTPRerank(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"tei", `[{"index":1,"score":0.9},{"index":0,"score":0.1}]`},
+		{"jina", `{"model":"jina-reranker-v2","results":[{"index":1,"relevance_score":0.9},{"index":0,"relevance_score":0.1}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/rerank" {
+					t.Errorf("path = %s, want /rerank", r.URL.Path)
+				}
+
+				var request struct {
+					Query string   `json:"query"`
+					Texts []string `json:"texts"`
+				}
+				json.NewDecoder(r.Body).Decode(&request)
+				if request.Query != "question" || len(request.Texts) != 2 {
+					t.Errorf("unexpected request %+v", request)
+				}
+
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			scores, err := NewHTTPReranker(server.URL, "", "").Rerank(context.Background(), "question", []string{"first", "second"})
+			if err != nil {
+				t.Fatalf("Rerank() error = %v", err)
+			}
+			if want := []float32{0.1, 0.9}; !reflect.DeepEqual(scores, want) {
+				t.Errorf("Rerank() = %v, want %v", scores, want)
+			}
+		})
+	}
+}
+
+func TestHTTPRerankMissingScore(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"index":0,"score":0.5}]`))
+	}))
+	defer server.Close()
+
+	if _, err := NewHTTPReranker(server.URL, "", "").Rerank(context.Background(), "q", []string{"a", "b"}); err == nil {
+		t.Errorf("Rerank() error = nil, want an error for the unscored document")
+	}
+}
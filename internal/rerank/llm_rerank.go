@@ -0,0 +1,98 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/elchemista/easy_rag/internal/llm"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultLLMConcurrency is the number of documents scored in parallel when Concurrency is not set
+const defaultLLMConcurrency = 4
+
+// ratingNumber matches the first number of the LLM answer
+var ratingNumber = regexp.MustCompile(`\d+(?:\.\d+)?`)
+
+// LLMReranker implements Reranker pointwise: the LLM rates each document on its own
+type LLMReranker struct {
+	LLM         llm.LLMService
+	Concurrency int // Documents scored in parallel
+}
+
+func NewLLMReranker(service llm.LLMService) *LLMReranker {
+	return &LLMReranker{
+		LLM:         service,
+		Concurrency: defaultLLMConcurrency,
+	}
+}
+
+// Rerank asks the LLM to rate the relevance of each document from 0 to 10 and returns
+// the ratings scaled to 0..1. A document whose rating can't be read scores 0. The first
+// failure, or the cancellation of ctx, stops the ratings not yet started.
+func (l *LLMReranker) Rerank(ctx context.Context, query string, documents []string) ([]float32, error) {
+	concurrency := l.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultLLMConcurrency
+	}
+
+	scores := make([]float32, len(documents))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(concurrency)
+
+	for i, document := range documents {
+		// Go blocks while the limit is reached, a failed or cancelled rerank stops here
+		if groupCtx.Err() != nil {
+			break
+		}
+		group.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				return err
+			}
+			score, err := l.score(groupCtx, query, document)
+			if err != nil {
+				return err
+			}
+			scores[i] = score
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// score rates a single document
+func (l *LLMReranker) score(ctx context.Context, query string, document string) (float32, error) {
+	answer, err := l.LLM.Generate(ctx, fmt.Sprintf("Rate how relevant the passage is to answer the question, "+
+		"from 0 (irrelevant) to 10 (fully answers it). Reply with the number only.\n\n"+
+		"Question: %s\n\nPassage: %s", query, document))
+	if err != nil {
+		return 0, fmt.Errorf("failed to rate document: %w", err)
+	}
+
+	return parseRating(answer), nil
+}
+
+// parseRating reads the first number of the answer as a 0..10 rating and scales it to 0..1
+func parseRating(answer string) float32 {
+	match := ratingNumber.FindString(answer)
+	if match == "" {
+		log.Printf("Reranker: no rating in LLM answer %q", answer)
+		return 0
+	}
+
+	rating, err := strconv.ParseFloat(match, 32)
+	if err != nil {
+		return 0
+	}
+	return float32(min(max(rating, 0), 10) / 10)
+}
@@ -0,0 +1,83 @@
+package rerank
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var _ Reranker = (*LLMReranker)(nil)
+
+// fakeLLM rates the passages containing "relevant" 8 and the others 1
+type fakeLLM struct{}
+
+func (fakeLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "Passage: relevant") {
+		return "8", nil
+	}
+	return "Rating: 1/10", nil
+}
+
+func (fakeLLM) GetModel() string { return "fake" }
+
+func TestLLMRerank(t *testing.T) {
+	scores, err := NewLLMReranker(fakeLLM{}).Rerank(context.Background(), "question", []string{"other", "relevant text"})
+	if err != nil {
+		t.Fatalf("Rerank() error = %v", err)
+	}
+	if want := []float32{0.1, 0.8}; !reflect.DeepEqual(scores, want) {
+		t.Errorf("Rerank() = %v, want %v", scores, want)
+	}
+}
+
+// failingLLM fails every rating and counts the calls
+type failingLLM struct {
+	calls atomic.Int32
+}
+
+func (f *failingLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	f.calls.Add(1)
+	return "", errors.New("unavailable")
+}
+
+func (f *failingLLM) GetModel() string { return "fake" }
+
+func TestLLMRerankStopsOnError(t *testing.T) {
+	service := &failingLLM{}
+	reranker := NewLLMReranker(service)
+	reranker.Concurrency = 1
+
+	if _, err := reranker.Rerank(context.Background(), "question", []string{"a", "b", "c", "d"}); err == nil {
+		t.Fatal("Rerank() error = nil, want the LLM failure")
+	}
+	if calls := service.calls.Load(); calls != 1 {
+		t.Errorf("LLM called %d times, want the ratings stopped after the first failure", calls)
+	}
+
+	// a cancelled context rates nothing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewLLMReranker(fakeLLM{}).Rerank(ctx, "question", []string{"a"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Rerank() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestParseRating(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   float32
+	}{
+		{"7", 0.7},
+		{"I'd say 9.5 out of 10", 0.95},
+		{"42", 1},
+		{"not relevant", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRating(tt.answer); got != tt.want {
+			t.Errorf("parseRating(%q) = %v, want %v", tt.answer, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package rerank
+
+import "context"
+
+// Reranker scores retrieved documents against the query, typically with a model
+// more precise (and slower) than the vector similarity used to retrieve them
+type Reranker interface {
+	// Rerank returns one relevance score per document, in the order of the documents.
+	// Higher scores are more relevant.
+	Rerank(ctx context.Context, query string, documents []string) ([]float32, error)
+}